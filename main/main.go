@@ -2,6 +2,7 @@ package main
 
 import (
 	"cloudflare-ping/ping"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -9,9 +10,24 @@ import (
 )
 
 const (
-	hostArgIndex = 0
-	argCount     = 1
-	usageExample = "sudo ./main/ping [-W waittime] [-c count] [-f] [-i wait] [-m ttl] [-s packetsize] [-t timeout] host"
+	hostArgIndex          = 0
+	argCount              = 1
+	usageExample          = "sudo ./main/ping [-W waittime] [-c count] [-f] [-A] [-q] [-O] [-n] [-4] [-6] [-a] [-D] [-e summary] [-R reresolve] [--max-loss percent] [--max-rtt ms] [-i wait] [-r rate] [-b burst] [-l preload] [-m ttl] [-s packetsize] [-t timeout] [-w deadline] [-P probe] [-S source] [-I interface] [-Q tos] host"
+	responderCommand      = "responder"
+	responderAddrArgIndex = 0
+	responderMaxArgCount  = 1
+	responderUsageExample = "sudo ./main/ping responder [-P probe] [-d delay] [-L loss] [-C corrupt] [-r ratelimit] [address]"
+)
+
+const (
+	// exit statuses, following 'ping' and sysexits(3)
+	exitSuccess    = 0  // a reply arrived and the run met its criteria
+	exitFailed     = 1  // the run did not meet its criteria (--max-loss, --max-rtt)
+	exitNoReply    = 2  // no reply arrived
+	exitUsage      = 64 // invalid flags or arguments (EX_USAGE)
+	exitNoHost     = 68 // the host could not be resolved (EX_NOHOST)
+	exitError      = 71 // a system error, such as failing to send (EX_OSERR)
+	exitPermission = 77 // not permitted to open the socket (EX_NOPERM)
 )
 
 // flagArg interface allows us to process the command-line
@@ -31,18 +47,60 @@ func usage() {
 	fmt.Fprintln(os.Stderr) // space for readability
 }
 
+// print responder usage to stderr
+func responderUsage(flags *flag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "\nUsage: %v\n\n", responderUsageExample)
+	flags.PrintDefaults()   // print flag defaults
+	fmt.Fprintln(os.Stderr) // space for readability
+}
+
 // main method
 func main() {
+	// run the responder subcommand if requested
+	if len(os.Args) > 1 && os.Args[1] == responderCommand {
+		respond(os.Args[2:])
+		return
+	}
 	p := parse()        // parse args
 	err := p.Validate() // check if valid
 	if err != nil {
 		fmt.Printf("Failed to ping: %v\n", err)
-		usage()    // print usage
-		os.Exit(1) // exit program
+		usage()                // print usage
+		os.Exit(exitCode(err)) // exit program
 	}
 	err = p.Start() // start pinging
 	if err != nil {
-		log.Fatalf("ping failure: %v\n", err)
+		log.Printf("ping failure: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+	err = p.Check() // check if the run succeeded
+	if err != nil && !errors.Is(err, ping.ErrNoReply) {
+		log.Printf("ping failure: %v\n", err) // no reply is clear from the stats
+	}
+	os.Exit(exitCode(err))
+}
+
+// maps an error of the ping or responder
+// to the exit status of the program
+func exitCode(err error) int {
+	var configErr *ping.ConfigError
+	var resolveErr *ping.ResolveError
+	var criteriaErr *ping.CriteriaError
+	switch {
+	case err == nil:
+		return exitSuccess
+	case errors.Is(err, ping.ErrNoReply):
+		return exitNoReply
+	case errors.As(err, &criteriaErr):
+		return exitFailed
+	case errors.As(err, &configErr):
+		return exitUsage
+	case errors.As(err, &resolveErr):
+		return exitNoHost
+	case errors.Is(err, os.ErrPermission):
+		return exitPermission
+	default:
+		return exitError
 	}
 }
 
@@ -54,25 +112,92 @@ func parse() *ping.Ping {
 		&p.TTL,
 		&p.Count,
 		&p.Timeout,
+		&p.Deadline,
 		&p.PacketSize,
 		&p.Flood,
+		&p.Adaptive,
+		&p.Quiet,
+		&p.Outstanding,
+		&p.Numeric,
+		&p.Summary,
+		&p.Reresolve,
+		&p.MaxLoss,
+		&p.MaxRTT,
 		&p.Wait,
+		&p.Rate,
+		&p.Burst,
+		&p.Preload,
 		&p.WaitTime,
+		&p.Probe,
+		&p.Source,
+		&p.Interface,
+		&p.IPv4Only,
+		&p.IPv6Only,
+		&p.AllAddresses,
+		&p.DualStack,
+		&p.TOS,
 	}
-	// parse each flag, each implements flag.Value
+	// parse each flag, each implements flag.Value,
+	// exiting with the usage status on invalid flags
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.Usage = usage
 	for _, f := range flags {
 		f.Init()
 		flag.Var(f, f.Flag(), f.Help())
 	}
-	flag.Parse()
+	parseFlags(flag.CommandLine, os.Args[1:])
 	// parse host name argument
 	args := flag.Args()
 	if len(args) != argCount {
 		// invalid number or order of arguments
 		usage()
-		os.Exit(1)
+		os.Exit(exitUsage)
 	}
 	p.HostName = args[hostArgIndex]
 	// return pointer to ping.Ping
 	return &p
 }
+
+// Parses the command-line arguments and flags passed
+// to the responder subcommand, then starts responding.
+func respond(args []string) {
+	r := ping.Responder{}
+	flags := flag.NewFlagSet(responderCommand, flag.ContinueOnError)
+	flags.Usage = func() { responderUsage(flags) }
+	for _, f := range []flagArg{&r.Probe, &r.Delay, &r.Loss, &r.Corrupt, &r.RateLimit} {
+		f.Init()
+		flags.Var(f, f.Flag(), f.Help())
+	}
+	parseFlags(flags, args)
+	// parse optional address argument
+	if flags.NArg() > responderMaxArgCount {
+		responderUsage(flags)
+		os.Exit(exitUsage)
+	}
+	if flags.NArg() > 0 {
+		r.Address = flags.Arg(responderAddrArgIndex)
+	}
+	err := r.Validate()
+	if err != nil {
+		fmt.Printf("Failed to respond: %v\n", err)
+		responderUsage(flags)
+		os.Exit(exitUsage)
+	}
+	err = r.Start()
+	if err != nil {
+		log.Printf("responder failure: %v\n", err)
+		os.Exit(exitCode(err))
+	}
+}
+
+// parses the flags in the arguments, where the flag set prints
+// the error and usage, exiting with the usage status if invalid
+func parseFlags(flags *flag.FlagSet, args []string) {
+	err := flags.Parse(args)
+	if err == flag.ErrHelp {
+		os.Exit(exitSuccess) // usage was requested
+	}
+	if err != nil {
+		os.Exit(exitUsage)
+	}
+}
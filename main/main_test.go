@@ -0,0 +1,36 @@
+package main
+
+import (
+	"cloudflare-ping/ping"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"success", nil, exitSuccess},
+		{"no reply", ping.ErrNoReply, exitNoReply},
+		{"wrapped no reply", fmt.Errorf("run: %w", ping.ErrNoReply), exitNoReply},
+		{"criteria", &ping.CriteriaError{Criterion: "max-loss", Limit: "10%", Value: "50%"}, exitFailed},
+		{"config", &ping.ConfigError{Err: errors.New("invalid")}, exitUsage},
+		{"resolve", &ping.ResolveError{Host: "nohost.invalid", Err: errors.New("no such host")}, exitNoHost},
+		{"socket permission", &ping.SocketError{Op: "get packet conn", Err: syscall.EPERM}, exitPermission},
+		{"socket", &ping.SocketError{Op: "get packet conn", Err: syscall.EADDRNOTAVAIL}, exitError},
+		{"send permission", &ping.SendError{Op: "write", Seq: 1, Err: syscall.EACCES}, exitPermission},
+		{"send", &ping.SendError{Op: "write", Seq: 1, Err: syscall.ENETUNREACH}, exitError},
+		{"permission", os.ErrPermission, exitPermission},
+		{"other", errors.New("failed to read"), exitError},
+	}
+	for _, test := range tests {
+		if code := exitCode(test.err); code != test.code {
+			t.Errorf("%v: exitCode(%v) = %v, expected %v", test.name, test.err, code, test.code)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package ping
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// Adaptive constants based off the man page for iputils 'ping'.
+	adaptiveFlag = "A"
+	adaptiveHelp = "Set the mode to adaptive. In adaptive mode, the interval\n" +
+		"between packets follows the round-trip time, where a packet is\n" +
+		"sent as soon as the reply to the previous one arrives, but no\n" +
+		"sooner than 10ms (or wait (-i) if set) after it. The wait for a\n" +
+		"missing reply backs off, up to the wait time (-W). If unset, the\n" +
+		"program will behave normally. This flag (-A) is incompatible\n" +
+		"with flood (-f) and rate (-r)."
+	adaptiveIntervalMin = 10 * time.Millisecond // min interval between packets, unless wait is set
+	adaptiveRTTWeight   = 8                     // weight of the smoothed rtt against a new rtt (like tcp)
+	adaptiveRTTFactor   = 2                     // times the smoothed rtt a reply is waited for
+	adaptiveBackoff     = 2                     // factor the wait grows by for each missing reply
+)
+
+// Adaptive is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type Adaptive bool
+
+// Init initializes an Adaptive instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Adaptive) Init() {
+}
+
+// String is used to format Adaptive's value and is required
+// to satisfy the flag.Value interface.
+func (a *Adaptive) String() string {
+	return fmt.Sprintf("value=%v", *a)
+}
+
+// Set will initialize Adaptive's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (a *Adaptive) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*a = Adaptive(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Adaptive.
+func (*Adaptive) Flag() string {
+	return adaptiveFlag
+}
+
+// Help gets the command-line help for Adaptive.
+func (*Adaptive) Help() string {
+	return adaptiveHelp
+}
+
+// IsBoolFlag is used to notify that Adaptive is
+// a boolean flag, so '-A' defaults to '-A=true' or '-A true'.
+func (*Adaptive) IsBoolFlag() bool {
+	return true
+}
+
+// sends "echo requests" in adaptive mode, where the next request
+// is sent as soon as the reply to the previous one arrives, but no
+// sooner than the min interval, and the wait for a missing reply
+// backs off up to the wait time, so low-latency links are measured
+// quickly without flooding lossy ones
+func (p *Ping) adaptiveSender(done <-chan bool, errors chan<- error) {
+	defer p.waitGroup.Done()
+	minInterval := adaptiveIntervalMin
+	if p.Wait.IsSet {
+		minInterval = time.Duration(p.Wait.Value)
+	}
+	maxTimeout := time.Duration(p.WaitTime)
+	timeout := maxTimeout // until an rtt is measured
+	var srtt time.Duration
+	start, ok, err := p.sendPreload(done)
+	if err != nil {
+		go func() { errors <- err }()
+		return
+	}
+	if !ok {
+		return // stop sending
+	}
+	// keep sending forever unless count is set (see shouldSend)
+	for i := start; p.shouldSend(i); i++ {
+		p.drainReceived() // replies to earlier requests
+		sendTime := time.Now()
+		err = p.send(i)
+		if err != nil {
+			go func() { errors <- err }()
+			return
+		}
+		// wait for the reply, or back off if it is missing
+		timer := time.NewTimer(timeout)
+		select {
+		case <-done:
+			timer.Stop()
+			return // stop sending
+		case <-p.recvNotify:
+			timer.Stop()
+			rtt := time.Since(sendTime)
+			if srtt == 0 {
+				srtt = rtt
+			} else {
+				srtt += (rtt - srtt) / adaptiveRTTWeight
+			}
+			timeout = adaptiveRTTFactor * srtt
+		case <-timer.C:
+			timeout *= adaptiveBackoff
+		}
+		if timeout < minInterval {
+			timeout = minInterval
+		}
+		if timeout > maxTimeout {
+			timeout = maxTimeout
+		}
+		// keep the min interval since the request
+		if wait := minInterval - time.Since(sendTime); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-done:
+				timer.Stop()
+				return // stop sending
+			case <-timer.C:
+			}
+		}
+	}
+	go func() { errors <- nil }() // finished successfully
+}
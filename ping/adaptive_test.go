@@ -0,0 +1,52 @@
+package ping
+
+import (
+	"testing"
+	"time"
+)
+
+// the slack allowed past an expected gap between sends,
+// for the scheduling of the sender and the echoConn
+const gapSlack = 30 * time.Millisecond
+
+// checks that each gap between sends is at least a duration,
+// and at most the slack past it
+func checkGaps(t *testing.T, times []time.Time, count int, gap time.Duration) {
+	t.Helper()
+	if len(times) != count {
+		t.Fatalf("expected %v packets sent, got %v", count, len(times))
+	}
+	for i, got := range gaps(times) {
+		if got < gap-time.Millisecond || got > gap+gapSlack {
+			t.Errorf("expected send %v about %v after the previous one, got %v", i+1, gap, got)
+		}
+	}
+}
+
+func TestAdaptiveKeepsMinInterval(t *testing.T) {
+	// replies arrive at once, so the min interval paces the sends
+	const interval = 40 * time.Millisecond
+	times, _ := runEcho(t, 0, WithAdaptive(), WithCount(5), WithInterval(interval))
+	checkGaps(t, times, 5, interval)
+}
+
+func TestAdaptiveSendsOnReply(t *testing.T) {
+	// the next request is sent as soon as the reply arrives
+	const rtt = 40 * time.Millisecond
+	times, p := runEcho(t, rtt, WithAdaptive(), WithCount(5), WithInterval(5*time.Millisecond))
+	checkGaps(t, times, 5, rtt)
+	if stats := p.statsSnapshot(); stats.received != 5 {
+		t.Errorf("expected 5 replies, got %v", stats.received)
+	}
+}
+
+func TestAdaptiveFallsBackToWaitTime(t *testing.T) {
+	// without replies, the next request waits for the wait time
+	const waitTime = 60 * time.Millisecond
+	times, p := runEcho(t, -1, WithAdaptive(), WithCount(4),
+		WithInterval(10*time.Millisecond), WithWaitTime(waitTime))
+	checkGaps(t, times, 4, waitTime)
+	if stats := p.statsSnapshot(); stats.received != 0 || stats.expired != 4 {
+		t.Errorf("expected 4 packets lost, got %v received and %v expired", stats.received, stats.expired)
+	}
+}
@@ -0,0 +1,114 @@
+package ping
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// AllAddresses constants, where 'ping' has no equivalent.
+	allAddressesFlag = "a"
+	allAddressesHelp = "Ping all addresses of the host. The host name is resolved\n" +
+		"to all of its addresses, in the family of -4, -6 or the\n" +
+		"source address (-S) if set, and each is pinged at the same\n" +
+		"time with its own statistics. If unset, only the first\n" +
+		"address of the host is pinged."
+)
+
+// AllAddresses is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type AllAddresses bool
+
+// Init initializes an AllAddresses instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*AllAddresses) Init() {
+}
+
+// String is used to format AllAddresses's value and is required
+// to satisfy the flag.Value interface.
+func (a *AllAddresses) String() string {
+	return fmt.Sprintf("value=%v", *a)
+}
+
+// Set will initialize AllAddresses's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (a *AllAddresses) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*a = AllAddresses(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for AllAddresses.
+func (*AllAddresses) Flag() string {
+	return allAddressesFlag
+}
+
+// Help gets the command-line help for AllAddresses.
+func (*AllAddresses) Help() string {
+	return allAddressesHelp
+}
+
+// IsBoolFlag is used to notify that AllAddresses is
+// a boolean flag, so '-a' defaults to '-a=true' or '-a true'.
+func (*AllAddresses) IsBoolFlag() bool {
+	return true
+}
+
+// pings every address of the host at the same time
+func (p *Ping) startAll() error {
+	addrs, err := p.resolveAll()
+	if err != nil {
+		return err
+	}
+	return p.startChildren(addrs, 0)
+}
+
+// pings addresses of the host at the same time, each by a child
+// ping with its own connection and statistics, which shares the
+// request of the Ping, where each child starts an offset after
+// the previous one
+func (p *Ping) startChildren(addrs []*net.IPAddr, offset time.Duration) error {
+	for _, addr := range addrs {
+		child := &Ping{Config: p.Config, hostOverride: addr, stop: p.stop, writer: p.writer}
+		child.AllAddresses = false
+		child.DualStack = false
+		err := child.init()
+		if err != nil {
+			// release the children initialized so far
+			for _, child := range p.children {
+				child.close()
+			}
+			p.children = nil
+			return fmt.Errorf("failed to initialize ping of %v: %w", addr, err)
+		}
+		p.children = append(p.children, child)
+	}
+	// print stats of every child if program interrupted
+	stopInterrupts := p.handleInterrupts()
+	defer stopInterrupts()
+	for _, child := range p.children {
+		child.printHeader()
+	}
+	errors := make(chan error, len(p.children))
+	for i, child := range p.children {
+		go func(child *Ping, delay time.Duration) {
+			time.Sleep(delay)
+			errors <- child.run()
+		}(child, time.Duration(i)*offset)
+	}
+	var err error
+	// wait for every child, keeping the first error
+	for range p.children {
+		if childErr := <-errors; childErr != nil && err == nil {
+			err = childErr
+		}
+	}
+	p.printStats()
+	return err
+}
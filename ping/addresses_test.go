@@ -0,0 +1,38 @@
+package ping
+
+import (
+	"errors"
+	"testing"
+)
+
+// creates a Ping of all addresses whose children
+// received the number of replies to 2 requests
+func newCheckPing(t *testing.T, received ...uint64) *Ping {
+	t.Helper()
+	p := newTestPing(t, true)
+	for _, n := range received {
+		child := newTestPing(t, true)
+		child.hostOverride = child.hostAddr
+		child.stats = rttStats{transmitted: 2, received: n}
+		p.children = append(p.children, child)
+	}
+	return p
+}
+
+func TestCheckAllAddresses(t *testing.T) {
+	if err := newCheckPing(t, 2, 0).Check(); err != nil {
+		t.Errorf("expected a reply from any address to succeed, got %v", err)
+	}
+	if err := newCheckPing(t, 0, 0).Check(); err != ErrNoReply {
+		t.Errorf("expected %v without any reply, got %v", ErrNoReply, err)
+	}
+	p := newCheckPing(t, 2, 0)
+	p.MaxLoss = MaxLoss{IsSet: true, Value: 10}
+	for _, child := range p.children {
+		child.MaxLoss = p.MaxLoss
+	}
+	var criteriaErr *CriteriaError
+	if err := p.Check(); !errors.As(err, &criteriaErr) || criteriaErr.Criterion != maxLossFlag {
+		t.Errorf("expected the address without a reply to exceed the max loss, got %v", err)
+	}
+}
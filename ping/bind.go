@@ -0,0 +1,119 @@
+package ping
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// gets the interface the Ping's sockets are bound to,
+// either from the interface flag or the zone of a
+// link-local host, returning nil if unbound
+func (p *Ping) boundInterface() (*net.Interface, error) {
+	switch {
+	case p.Interface.IsSet:
+		return net.InterfaceByName(p.Interface.Value)
+	case p.hostAddr.Zone != "":
+		return net.InterfaceByName(p.hostAddr.Zone)
+	default:
+		return nil, nil
+	}
+}
+
+// controls the Ping's sockets before they are bound,
+// binding them to the interface if set
+func (p *Ping) control(network, address string, c syscall.RawConn) error {
+	if p.iface == nil {
+		return nil
+	}
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		err = bindToInterface(fd, p.iface, isIPv4Network(network))
+	})
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// controls the Ping's dialed sockets before they connect, binding
+// them to the interface and setting their tos if set
+func (p *Ping) dialControl(network, address string, c syscall.RawConn) error {
+	if err := p.control(network, address, c); err != nil || !p.TOS.IsSet {
+		return err
+	}
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		err = setTOS(fd, p.TOS.Value, isIPv4Network(network))
+	})
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// reports if a network passed to a socket's control
+// function is IPv4 (ex. ip4, udp4, tcp4)
+func isIPv4Network(network string) bool {
+	return strings.HasSuffix(network, ipv4NetworkSuffix)
+}
+
+// listens for packets on the network using the source
+// address and interface if set, where the address
+// is used if there is no source address
+func (p *Ping) listen(network, address string) (net.PacketConn, error) {
+	if p.Source.IsSet {
+		address = p.Source.Value.String()
+	}
+	if network == udpNetwork {
+		address = net.JoinHostPort(address, udpAnyPort) // udp also needs a port
+	}
+	lc := net.ListenConfig{Control: p.control}
+	return lc.ListenPacket(context.Background(), network, address)
+}
+
+// dials a connection, which is what tcp and http probes
+// need of a *net.Dialer
+type contextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// gets the dialer of tcp and http probes, which
+// is the overriding dialer if set
+func (p *Ping) probeDialer() contextDialer {
+	if p.dialOverride != nil {
+		return p.dialOverride
+	}
+	return p.dialer()
+}
+
+// gets a dialer for probes over tcp that gives up once the
+// wait time is exceeded, using the source address,
+// interface and tos if set
+func (p *Ping) dialer() *net.Dialer {
+	dialer := &net.Dialer{
+		Timeout: p.probeTimeout(),
+		Control: p.dialControl,
+	}
+	if p.Source.IsSet {
+		dialer.LocalAddr = &net.TCPAddr{IP: p.Source.Value}
+	}
+	return dialer
+}
+
+// describes where packets are sent from for output,
+// empty if the source address and interface are unset
+func (p *Ping) describeSource() string {
+	var from string
+	if p.Source.IsSet {
+		from += " " + p.Source.Value.String()
+	}
+	if p.iface != nil {
+		from += " " + p.iface.Name
+	}
+	if from == "" {
+		return ""
+	}
+	return " from" + from
+}
@@ -0,0 +1,19 @@
+//go:build darwin
+// +build darwin
+
+package ping
+
+import (
+	"net"
+	"syscall"
+)
+
+// binds a socket to an interface with IP_BOUND_IF (ipv4)
+// or IPV6_BOUND_IF (ipv6), so packets are only sent
+// from and received on that interface
+func bindToInterface(fd uintptr, ifi *net.Interface, isIPv4 bool) error {
+	if isIPv4 {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_BOUND_IF, ifi.Index)
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_BOUND_IF, ifi.Index)
+}
@@ -0,0 +1,15 @@
+//go:build linux
+// +build linux
+
+package ping
+
+import (
+	"net"
+	"syscall"
+)
+
+// binds a socket to an interface with SO_BINDTODEVICE, so packets
+// are only sent from and received on that interface
+func bindToInterface(fd uintptr, ifi *net.Interface, isIPv4 bool) error {
+	return syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, ifi.Name)
+}
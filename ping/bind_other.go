@@ -0,0 +1,14 @@
+//go:build !darwin && !linux
+// +build !darwin,!linux
+
+package ping
+
+import (
+	"net"
+)
+
+// binding to an interface is not supported on this system,
+// so an error is returned
+func bindToInterface(fd uintptr, ifi *net.Interface, isIPv4 bool) error {
+	return errInterfaceBindUnsupported
+}
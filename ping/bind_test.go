@@ -0,0 +1,144 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/ipv4"
+)
+
+// starts reflecting udp datagrams back to their sender on the
+// loopback address, returning its port and the addresses the
+// datagrams came from
+func udpReflector(t *testing.T) (port string, peers <-chan net.Addr) {
+	t.Helper()
+	conn, err := net.ListenPacket(udpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	addrs := make(chan net.Addr, 16)
+	go func() {
+		buffer := make([]byte, icmpPacketMaxSize)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			select {
+			case addrs <- addr:
+			default:
+			}
+			conn.WriteTo(buffer[:n], addr)
+		}
+	}()
+	return strconv.Itoa(conn.LocalAddr().(*net.UDPAddr).Port), addrs
+}
+
+// gets the name of a loopback interface
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatalf("failed to get interfaces: %v", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
+
+func TestSourceAddress(t *testing.T) {
+	// any address of 127.0.0.0/8 is local on linux, but not everywhere
+	const source = "127.0.0.2"
+	conn, err := net.ListenPacket(udpNetwork, source+":0")
+	if err != nil {
+		t.Skipf("%v is not a local address: %v", source, err)
+	}
+	conn.Close()
+	port, peers := udpReflector(t)
+	output := runProbe(t, "127.0.0.1", WithProbe("udp:"+port), WithSource(source))
+	if !strings.Contains(output, "1 packets transmitted, 1 packets received") {
+		t.Errorf("expected the reply to arrive, got:\n%v", output)
+	}
+	if peer := (<-peers).(*net.UDPAddr); peer.IP.String() != source {
+		t.Errorf("expected the datagram to be sent from %v, got %v", source, peer.IP)
+	}
+}
+
+func TestSourceFamilyMismatch(t *testing.T) {
+	_, err := New("::1", WithSource("127.0.0.1"))
+	if !errors.Is(err, errSourceFamilyInvalid) {
+		t.Errorf("expected %v, got %v", errSourceFamilyInvalid, err)
+	}
+}
+
+func TestInterfaceBinding(t *testing.T) {
+	name := loopbackInterface(t)
+	port, _ := udpReflector(t)
+	var output strings.Builder
+	p, err := New("127.0.0.1", WithProbe("udp:"+port), WithInterface(name),
+		WithCount(1), WithNumeric(), WithOutput(&output))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	_, err = p.Run(context.Background())
+	if errors.Is(err, errInterfaceBindUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	if !strings.Contains(output.String(), " from "+name) {
+		t.Errorf("expected the header to name the interface, got:\n%v", output.String())
+	}
+	if !strings.Contains(output.String(), "1 packets transmitted, 1 packets received") {
+		t.Errorf("expected the reply to arrive over the interface, got:\n%v", output.String())
+	}
+	if _, err := New("127.0.0.1", WithInterface("no-such-interface0")); !errors.Is(err, errInterfaceInvalid) {
+		t.Errorf("expected %v, got %v", errInterfaceInvalid, err)
+	}
+}
+
+func TestTOS(t *testing.T) {
+	const tos = 0xb8
+	p := &Ping{Config: DefaultConfig("127.0.0.1")}
+	p.Probe.Set("udp:7")
+	p.TOS = TypeOfService{IsSet: true, Value: tos}
+	if err := p.initSession(); err != nil {
+		t.Fatalf("failed to initialize ping: %v", err)
+	}
+	defer p.cancel()
+	// the udp socket of the probes is marked
+	if err := p.initUDP(); err != nil {
+		t.Fatalf("failed to initialize udp: %v", err)
+	}
+	defer p.conn.Close()
+	if got, err := ipv4.NewPacketConn(p.conn).TOS(); err != nil || got != tos {
+		t.Errorf("expected the udp socket to have tos %#x, got %#x (%v)", tos, got, err)
+	}
+	// and so are the connections of tcp probes
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	conn, err := p.dialer().Dial(tcpNetwork, listener.Addr().String())
+	if errors.Is(err, errTOSUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if got, err := ipv4.NewConn(conn).TOS(); err != nil || got != tos {
+		t.Errorf("expected the tcp connection to have tos %#x, got %#x (%v)", tos, got, err)
+	}
+}
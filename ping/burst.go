@@ -0,0 +1,63 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// Burst constants for the rate-controlled sender.
+	burstFlag = "b"
+	burstHelp = "Set the number of packets that can be sent back-to-back, like\n" +
+		"the size of a token bucket. Packets are sent in a burst at the\n" +
+		"start, and after the sender falls behind. If unset, packets are\n" +
+		"evenly spaced (a burst of 1)."
+	burstDefault = 1
+	burstInvalid = "burst must be greater than 0"
+)
+
+var (
+	// error for invalid burst
+	errBurstInvalid = errors.New(burstInvalid)
+)
+
+// Burst is a wrapper around an unsigned integer
+// to use for command-line argument flag parsing.
+type Burst uint32
+
+// Init initializes a Burst instance by setting
+// its value to the default burst.
+func (b *Burst) Init() {
+	*b = Burst(burstDefault)
+}
+
+// String is used to format Burst's value and is required
+// to satisfy the flag.Value interface.
+func (b *Burst) String() string {
+	return fmt.Sprintf("value=%v", *b)
+}
+
+// Set will initialize Burst's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (b *Burst) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res <= 0 {
+		return errBurstInvalid
+	}
+	*b = Burst(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Burst.
+func (*Burst) Flag() string {
+	return burstFlag
+}
+
+// Help gets the command-line help for Burst.
+func (*Burst) Help() string {
+	return burstHelp
+}
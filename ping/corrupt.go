@@ -0,0 +1,64 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// Corrupt constants for the responder.
+	corruptFlag = "C"
+	corruptHelp = "Set the percentage of replies to corrupt by flipping\n" +
+		"the bits of a random payload byte. The number can be a fraction\n" +
+		"(ex. 0.5). If unset, no replies are corrupted."
+	corruptInvalid      = "corrupt must be between 0 and 100"
+	corruptInputBitSize = 64 // float64 accepted as input, so need 64 bits
+	corruptMax          = 100
+)
+
+var (
+	// error for invalid corrupt
+	errCorruptInvalid = errors.New(corruptInvalid)
+)
+
+// Corrupt is a wrapper around a percentage
+// to use for command-line argument flag parsing.
+type Corrupt float64
+
+// Init initializes a Corrupt instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Corrupt) Init() {
+}
+
+// String is used to format Corrupt's value and is required
+// to satisfy the flag.Value interface.
+func (c *Corrupt) String() string {
+	return fmt.Sprintf("value=%v%%", float64(*c))
+}
+
+// Set will initialize Corrupt's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (c *Corrupt) Set(val string) error {
+	res, err := strconv.ParseFloat(val, corruptInputBitSize)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(res) || res < 0 || res > corruptMax {
+		return errCorruptInvalid
+	}
+	*c = Corrupt(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Corrupt.
+func (*Corrupt) Flag() string {
+	return corruptFlag
+}
+
+// Help gets the command-line help for Corrupt.
+func (*Corrupt) Help() string {
+	return corruptHelp
+}
@@ -0,0 +1,68 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// Deadline constants based off the man page for iputils 'ping'.
+	deadlineFlag = "w"
+	deadlineHelp = "Set the deadline in seconds for the whole run, after which the\n" +
+		"program exits however many packets were sent or received. If count\n" +
+		"(-c) is also set, packets are sent until count replies arrive rather\n" +
+		"than count packets are sent, stopping early once they have. If unset,\n" +
+		"the program will behave normally."
+	deadlineInvalid = "deadline must be greater than 0"
+)
+
+var (
+	// error for invalid deadline
+	errDeadlineInvalid = errors.New(deadlineInvalid)
+)
+
+// Deadline is a wrapper around a boolean and a time.Duration
+// to use for command-line argument flag parsing.
+type Deadline struct {
+	IsSet bool
+	Value time.Duration
+}
+
+// Init initializes a Deadline instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Deadline) Init() {
+}
+
+// String is used to format Deadline's value and is required
+// to satisfy the flag.Value interface.
+func (d *Deadline) String() string {
+	return fmt.Sprintf("set=%v, value=%v", d.IsSet, d.Value)
+}
+
+// Set will initialize Deadline's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (d *Deadline) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res <= 0 {
+		return errDeadlineInvalid
+	}
+	d.IsSet = true
+	d.Value = time.Second * time.Duration(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Deadline.
+func (*Deadline) Flag() string {
+	return deadlineFlag
+}
+
+// Help gets the command-line help for Deadline.
+func (*Deadline) Help() string {
+	return deadlineHelp
+}
@@ -0,0 +1,77 @@
+package ping
+
+import (
+	"testing"
+	"time"
+)
+
+// runs a ping against an echoConn, returning how long it ran
+func timeEcho(t *testing.T, delay time.Duration, opts ...Option) (time.Duration, []time.Time, *Ping) {
+	t.Helper()
+	start := time.Now()
+	times, p := runEcho(t, delay, opts...)
+	return time.Since(start), times, p
+}
+
+func TestDeadlineBeforeCountReplies(t *testing.T) {
+	// no replies arrive, so the sends go on past count until the deadline
+	const deadline = 150 * time.Millisecond
+	elapsed, times, p := timeEcho(t, -1, WithCount(3),
+		WithInterval(20*time.Millisecond), WithDeadline(deadline))
+	if elapsed < deadline || elapsed > deadline+4*gapSlack {
+		t.Errorf("expected the run to end at the %v deadline, took %v", deadline, elapsed)
+	}
+	if len(times) <= 3 {
+		t.Errorf("expected more than count packets sent before the deadline, got %v", len(times))
+	}
+	if stats := p.statsSnapshot(); stats.received != 0 {
+		t.Errorf("expected no replies, got %v", stats.received)
+	}
+}
+
+func TestDeadlineAfterCountReplies(t *testing.T) {
+	// count replies arrive, so the run ends well before the deadline
+	elapsed, times, p := timeEcho(t, 0, WithCount(3),
+		WithInterval(20*time.Millisecond), WithDeadline(5*time.Second))
+	if elapsed > time.Second {
+		t.Errorf("expected the run to end once count replies arrived, took %v", elapsed)
+	}
+	if len(times) != 3 {
+		t.Errorf("expected 3 packets sent, got %v", len(times))
+	}
+	if stats := p.statsSnapshot(); stats.received != 3 {
+		t.Errorf("expected 3 replies, got %v", stats.received)
+	}
+}
+
+func TestLingerEndsOnLastReply(t *testing.T) {
+	// the last reply arrives long before the wait time
+	const rtt = 30 * time.Millisecond
+	elapsed, times, p := timeEcho(t, rtt, WithCount(3),
+		WithInterval(10*time.Millisecond), WithWaitTime(5*time.Second))
+	if len(times) != 3 {
+		t.Fatalf("expected 3 packets sent, got %v", len(times))
+	}
+	if stats := p.statsSnapshot(); stats.received != 3 {
+		t.Errorf("expected 3 replies, got %v", stats.received)
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected the run to end on the last reply, took %v", elapsed)
+	}
+}
+
+func TestLingerEndsOnLastExpiry(t *testing.T) {
+	// no replies arrive, so the run ends once the last packet expired
+	const waitTime = 100 * time.Millisecond
+	elapsed, times, p := timeEcho(t, -1, WithCount(2),
+		WithInterval(10*time.Millisecond), WithWaitTime(waitTime))
+	if len(times) != 2 {
+		t.Fatalf("expected 2 packets sent, got %v", len(times))
+	}
+	if stats := p.statsSnapshot(); stats.expired != 2 {
+		t.Errorf("expected 2 packets expired, got %v", stats.expired)
+	}
+	if want := times[1].Sub(times[0]) + waitTime; elapsed < want || elapsed > want+4*gapSlack {
+		t.Errorf("expected the run to end once the last packet expired, about %v, took %v", want, elapsed)
+	}
+}
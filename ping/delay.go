@@ -0,0 +1,61 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// Delay constants for the responder.
+	delayFlag = "d"
+	delayHelp = "Set the time in milliseconds to delay each reply by.\n" +
+		"If unset, replies are sent as soon as requests are received."
+	delayInvalid = "delay must be greater than or equal to 0"
+)
+
+var (
+	// error for invalid delay
+	errDelayInvalid = errors.New(delayInvalid)
+)
+
+// Delay is a wrapper around a time.Duration
+// to use for command-line argument flag parsing.
+type Delay time.Duration
+
+// Init initializes a Delay instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Delay) Init() {
+}
+
+// String is used to format Delay's value and is required
+// to satisfy the flag.Value interface.
+func (d *Delay) String() string {
+	return fmt.Sprintf("value=%v", time.Duration(*d))
+}
+
+// Set will initialize Delay's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (d *Delay) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res < 0 {
+		return errDelayInvalid
+	}
+	*d = Delay(time.Millisecond * time.Duration(res))
+	return nil
+}
+
+// Flag gets the command-line flag used for Delay.
+func (*Delay) Flag() string {
+	return delayFlag
+}
+
+// Help gets the command-line help for Delay.
+func (*Delay) Help() string {
+	return delayHelp
+}
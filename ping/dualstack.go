@@ -0,0 +1,156 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// DualStack constants for comparing ipv4 and ipv6.
+	dualStackFlag = "D"
+	dualStackHelp = "Set the mode to dual-stack. In dual-stack mode, the first\n" +
+		"IPv4 and IPv6 addresses of the host are pinged at the same\n" +
+		"time, interleaved, and the loss and round-trip times of IPv6\n" +
+		"are compared to IPv4 after the statistics of each. If unset,\n" +
+		"only one address is pinged. This flag (-D) is incompatible\n" +
+		"with -4, -6, -a, -R and source (-S)."
+	dualStackNotDual = "host must have both an IPv4 and an IPv6 address"
+)
+
+var (
+	// error for a host without both families of addresses
+	errDualStackNotDual = errors.New(dualStackNotDual)
+)
+
+// DualStack is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type DualStack bool
+
+// Init initializes a DualStack instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*DualStack) Init() {
+}
+
+// String is used to format DualStack's value and is required
+// to satisfy the flag.Value interface.
+func (d *DualStack) String() string {
+	return fmt.Sprintf("value=%v", *d)
+}
+
+// Set will initialize DualStack's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (d *DualStack) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*d = DualStack(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for DualStack.
+func (*DualStack) Flag() string {
+	return dualStackFlag
+}
+
+// Help gets the command-line help for DualStack.
+func (*DualStack) Help() string {
+	return dualStackHelp
+}
+
+// IsBoolFlag is used to notify that DualStack is
+// a boolean flag, so '-D' defaults to '-D=true' or '-D true'.
+func (*DualStack) IsBoolFlag() bool {
+	return true
+}
+
+// checks that the flags of the Ping are compatible with dual-stack
+// mode, where each address family is forced by the mode
+func (p *Ping) validateDualStack() error {
+	if !bool(p.DualStack) {
+		return nil
+	}
+	incompatible := []struct {
+		flag string
+		set  bool
+	}{
+		{ipv4OnlyFlag, bool(p.IPv4Only)},
+		{ipv6OnlyFlag, bool(p.IPv6Only)},
+		{allAddressesFlag, bool(p.AllAddresses)},
+		{reresolveFlag, p.Reresolve.IsSet},
+		{sourceFlag, p.Source.IsSet},
+	}
+	for _, other := range incompatible {
+		if other.set {
+			return fmt.Errorf("incompatible flags: -%v and -%v", dualStackFlag, other.flag)
+		}
+	}
+	return nil
+}
+
+// pings the first ipv4 and ipv6 addresses of the host at the
+// same time, where the ipv6 pings are sent halfway between the
+// ipv4 pings, so both families see the same conditions
+func (p *Ping) startDualStack() error {
+	addrs, err := p.resolveDualStack()
+	if err != nil {
+		return err
+	}
+	return p.startChildren(addrs, p.sendInterval()/2)
+}
+
+// resolves the first ipv4 and ipv6 addresses of the host,
+// returning a *ResolveError wrapping the error of the resolver,
+// or errDualStackNotDual if the host lacks either family
+func (p *Ping) resolveDualStack() ([]*net.IPAddr, error) {
+	addrs, err := p.lookupAll(context.Background(), ipv4Network, ipv6Network)
+	if err != nil {
+		return nil, err
+	}
+	// ordered by network, so the first is ipv4 and the last ipv6, if any
+	ipv4Addr, ipv6Addr := addrs[0], addrs[len(addrs)-1]
+	if ipv4Addr.IP.To4() == nil || ipv6Addr.IP.To4() != nil {
+		return nil, &ResolveError{Host: p.HostName, Err: errDualStackNotDual}
+	}
+	return []*net.IPAddr{ipv4Addr, ipv6Addr}, nil
+}
+
+// prints the difference in loss and round-trip times of
+// ipv6 compared to ipv4, after the stats of each
+func (p *Ping) printComparison() {
+	ipv4Stats, ipv6Stats := p.children[0].statsSnapshot(), p.children[1].statsSnapshot()
+	fmt.Fprintf(p.output(), "\n--- %v ipv6 compared to ipv4 ---\n", p.HostName)
+	if ipv4Stats.transmitted == 0 || ipv6Stats.transmitted == 0 {
+		fmt.Fprintln(p.output(), "<no packets sent>")
+		return // no packets, so nothing to compare
+	}
+	ipv4Loss, ipv6Loss := ipv4Stats.loss(), ipv6Stats.loss()
+	fmt.Fprintf(p.output(), "packet loss ipv4/ipv6 = %.1f%%/%.1f%%, difference = %+.1f%%\n",
+		ipv4Loss, ipv6Loss, ipv6Loss-ipv4Loss)
+	if ipv4Stats.received == 0 || ipv6Stats.received == 0 {
+		return // no round-trip times to compare
+	}
+	fmt.Fprintf(p.output(), "round-trip min/avg/max/stddev difference = %v/%v/%v/%v\n",
+		signedDuration(ipv6Stats.min-ipv4Stats.min), signedDuration(ipv6Stats.avg()-ipv4Stats.avg()),
+		signedDuration(ipv6Stats.max-ipv4Stats.max), signedDuration(ipv6Stats.stdDev()-ipv4Stats.stdDev()))
+}
+
+// gets a copy of the stats of the whole run
+func (p *Ping) statsSnapshot() rttStats {
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	return p.stats
+}
+
+// formats a duration with its sign, as a difference
+func signedDuration(d time.Duration) string {
+	if d < 0 {
+		return d.String()
+	}
+	return "+" + d.String()
+}
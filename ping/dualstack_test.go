@@ -0,0 +1,63 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+)
+
+// represents a resolver whose answers for the host
+// can change, or fail with an error if set
+type staticResolver struct {
+	addrs   []net.IPAddr // addresses of every host
+	err     error        // if set, error of every lookup
+	lookups int          // lookups of addresses
+	mux     sync.Mutex
+}
+
+func (r *staticResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return append([]net.IPAddr(nil), r.addrs...), nil
+}
+
+func (r *staticResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+// sets the addresses of every host
+func (r *staticResolver) setAddrs(addrs ...string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.addrs = nil
+	for _, addr := range addrs {
+		r.addrs = append(r.addrs, net.IPAddr{IP: net.ParseIP(addr)})
+	}
+}
+
+func TestValidateDualStack(t *testing.T) {
+	resolver := &staticResolver{}
+	resolver.setAddrs("2001:db8::1", "192.0.2.1")
+	if _, err := New("dual.example", WithDualStack(), WithResolver(resolver)); err != nil {
+		t.Errorf("expected a host with both families to be valid, got %v", err)
+	}
+	resolver.setAddrs("192.0.2.1")
+	_, err := New("single.example", WithDualStack(), WithResolver(resolver))
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) || !errors.Is(err, errDualStackNotDual) {
+		t.Errorf("expected a *ResolveError wrapping %v, got %v", errDualStackNotDual, err)
+	}
+	// a failing resolver is not mistaken for a single-stack host
+	dnsErr := &net.DNSError{Err: "server misbehaving", Name: "dual.example", IsTemporary: true}
+	resolver.err = dnsErr
+	_, err = New("dual.example", WithDualStack(), WithResolver(resolver))
+	if !errors.As(err, &resolveErr) || !errors.Is(err, dnsErr) || errors.Is(err, errDualStackNotDual) {
+		t.Errorf("expected a *ResolveError wrapping %v, got %v", dnsErr, err)
+	}
+}
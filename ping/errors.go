@@ -0,0 +1,93 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ConfigError is returned when the Ping request is invalid
+// (see Validate), wrapping the reason it is invalid.
+type ConfigError struct {
+	Err error // reason the request is invalid
+}
+
+// Error gets the reason the request is invalid.
+func (e *ConfigError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap gets the reason the request is invalid.
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ResolveError is returned when the host name cannot be
+// resolved into the addresses to ping.
+type ResolveError struct {
+	Host string // host name that failed to resolve
+	Err  error  // reason it failed to resolve
+}
+
+// Error describes the host name and the reason it failed to resolve.
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("failed to resolve %v: %v", e.Host, e.Err)
+}
+
+// Unwrap gets the reason the host name failed to resolve.
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+// SocketError is returned when the socket probes are sent and
+// received on cannot be opened or set up, where
+// errors.Is(err, os.ErrPermission) reports if more privileges
+// are needed (ex. sudo for a raw icmp socket).
+type SocketError struct {
+	Op  string // operation that failed
+	Err error  // reason the operation failed
+}
+
+// Error describes the operation and the reason it failed.
+func (e *SocketError) Error() string {
+	return fmt.Sprintf("failed to %v: %v", e.Op, e.Err)
+}
+
+// Unwrap gets the reason the operation failed.
+func (e *SocketError) Unwrap() error {
+	return e.Err
+}
+
+// SendError is returned when a probe cannot be sent to the host.
+type SendError struct {
+	Op  string // operation that failed
+	Seq uint64 // sequence of the probe
+	Err error  // reason the probe failed to send
+}
+
+// Error describes the operation, the sequence and the reason it failed.
+func (e *SendError) Error() string {
+	return fmt.Sprintf("failed to %v (seq %v): %v", e.Op, e.Seq, e.Err)
+}
+
+// Unwrap gets the reason the probe failed to send.
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrNoReply is returned by Check when no reply arrived.
+	ErrNoReply = errors.New("no reply received")
+)
+
+// CriteriaError is returned by Check when the statistics of a
+// run do not meet its success criteria (max loss or max rtt).
+type CriteriaError struct {
+	Criterion string // flag of the criterion (ex. max-loss)
+	Limit     string // limit set for the criterion
+	Value     string // value of the run that exceeded it
+}
+
+// Error describes the criterion, its limit and the value that exceeded it.
+func (e *CriteriaError) Error() string {
+	return fmt.Sprintf("%v exceeded: %v > %v", e.Criterion, e.Value, e.Limit)
+}
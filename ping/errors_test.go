@@ -0,0 +1,73 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConfigError(t *testing.T) {
+	_, err := New("127.0.0.1", WithCount(0))
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || !errors.Is(err, errCountInvalid) {
+		t.Errorf("expected a *ConfigError wrapping %v, got %v", errCountInvalid, err)
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	// the .invalid top-level domain never resolves (RFC 2606)
+	_, err := New("no-such-host.invalid")
+	var resolveErr *ResolveError
+	if !errors.As(err, &resolveErr) || resolveErr.Host != "no-such-host.invalid" {
+		t.Fatalf("expected a *ResolveError of the host, got %v", err)
+	}
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) {
+		t.Errorf("expected the *net.DNSError of the resolver, got %#v", resolveErr.Err)
+	}
+}
+
+func TestSocketError(t *testing.T) {
+	// an address of TEST-NET-3 (RFC 5737), which is never local
+	p, err := New("127.0.0.1", WithProbe("udp:7"), WithSource("203.0.113.9"),
+		WithCount(1), WithOutput(io.Discard))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	_, err = p.Run(context.Background())
+	var socketErr *SocketError
+	if !errors.As(err, &socketErr) || !errors.Is(err, syscall.EADDRNOTAVAIL) {
+		t.Errorf("expected a *SocketError wrapping %v, got %v", syscall.EADDRNOTAVAIL, err)
+	}
+}
+
+func TestSendError(t *testing.T) {
+	p := newTestPing(t, true)
+	p.conn.(*echoConn).writeErr = syscall.ENETUNREACH
+	err := p.send(3)
+	var sendErr *SendError
+	if !errors.As(err, &sendErr) || sendErr.Seq != 3 || !errors.Is(err, syscall.ENETUNREACH) {
+		t.Errorf("expected a *SendError of sequence 3 wrapping %v, got %v", syscall.ENETUNREACH, err)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	p := newTestPing(t, true)
+	if err := p.Check(); err != ErrNoReply {
+		t.Errorf("expected %v without a reply, got %v", ErrNoReply, err)
+	}
+	p.stats = rttStats{transmitted: 1, received: 1, min: time.Second, max: time.Second, mean: float64(time.Second)}
+	p.MaxRTT = MaxRTT{IsSet: true, Value: time.Millisecond}
+	var criteriaErr *CriteriaError
+	if err := p.Check(); !errors.As(err, &criteriaErr) || criteriaErr.Criterion != maxRTTFlag {
+		t.Errorf("expected a *CriteriaError of %v, got %v", maxRTTFlag, err)
+	}
+	p.MaxRTT = MaxRTT{}
+	if err := p.Check(); err != nil {
+		t.Errorf("expected the run to succeed, got %v", err)
+	}
+}
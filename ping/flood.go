@@ -16,7 +16,7 @@ const (
 	floodHelp = "Set the mode to flood. In flood mode, packets are output\n" +
 		"100 times per second plus as fast as they are received.\n" +
 		"If unset, the program will behave normally. This flag (-f)\n" +
-		"is incompatible with wait (-i)."
+		"is incompatible with wait (-i), rate (-r) and adaptive (-A)."
 	floodTimesPerSecond = 100
 )
 
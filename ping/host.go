@@ -1,19 +1,26 @@
 package ping
 
 import (
+	"context"
 	"errors"
 	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
 const (
 	// constants for resolving addresses as ipv4, ipv6
 	ipv4Network          = "ip4"
 	ipv6Network          = "ip6"
+	ipv4NetworkSuffix    = "4" // suffix of ipv4 networks (ex. tcp4)
+	ipv6NetworkSuffix    = "6" // suffix of ipv6 networks (ex. tcp6)
 	ipv4ICMPNetwork      = "ip4:icmp"
 	ipv6ICMPNetwork      = "ip6:ipv6-icmp"
 	ipv4BindAddress      = "0.0.0.0" // capture all ipv4 addresses
 	ipv6BindAddress      = "::"      // capture all ipv6 addresses
 	hostInvalid          = "invalid IPv4 or IPv6 address"
+	hostNoFamilyAddress  = "host has no address in the family used (-4, -6 or -S)"
 	ianaProtocolIPv4ICMP = 1
 	ianaProtocolIPv6ICMP = 58
 )
@@ -21,23 +28,195 @@ const (
 var (
 	// error for invalid host
 	errHostInvalid = errors.New(hostInvalid)
+	// error for a host without an address of the family used
+	errHostNoFamilyAddress = errors.New(hostNoFamilyAddress)
 )
 
 // ResolveHost attempts to resolve a string hostname
 // into an IPv4 or IPv6 address.
 // Returns the ip addr pointer, a boolean 'true' if
-// the address is IPv4, and an error if anything went wrong.
+// the address is IPv4, and the error of the resolver
+// (ex. a *net.DNSError) if anything went wrong.
 func ResolveHost(host string) (*net.IPAddr, bool, error) {
-	// try to resolve as IPv4
-	ipAddr, err := net.ResolveIPAddr(ipv4Network, host)
-	if err == nil {
-		return ipAddr, true, nil
-	}
-	// try to resolve as IPv6
-	ipAddr, err = net.ResolveIPAddr(ipv6Network, host)
-	if err == nil {
-		return ipAddr, false, nil
+	return resolveHost(host, ipv4Network, ipv6Network)
+}
+
+// resolves a string hostname into an address of the first
+// of the networks (ip4, ip6) it has one in, where the
+// boolean is 'true' if the address is IPv4, returning the
+// error of the resolver for the first network otherwise
+func resolveHost(host string, networks ...string) (*net.IPAddr, bool, error) {
+	var firstErr error
+	for _, network := range networks {
+		ipAddr, err := net.ResolveIPAddr(network, host)
+		if err == nil {
+			return ipAddr, network == ipv4Network, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
 	}
 	// failed to resolve
-	return ipAddr, false, errHostInvalid
+	return nil, false, firstErr
+}
+
+// gets the networks the host is resolved in, in order of
+// preference, which are limited to ipv4 or ipv6 if forced
+// or if the source address is set
+func (p *Ping) networks() []string {
+	switch {
+	case bool(p.IPv4Only):
+		return []string{ipv4Network}
+	case bool(p.IPv6Only):
+		return []string{ipv6Network}
+	case p.Source.IsSet && p.Source.Value.To4() != nil:
+		return []string{ipv4Network}
+	case p.Source.IsSet:
+		return []string{ipv6Network}
+	default:
+		return []string{ipv4Network, ipv6Network}
+	}
+}
+
+// resolves the host name into the address pinged, in the
+// networks of the Ping, unless the address is overridden
+func (p *Ping) resolveHost() (*net.IPAddr, bool, error) {
+	if p.hostOverride != nil {
+		return p.hostOverride, p.hostOverride.IP.To4() != nil, nil
+	}
+	addrs, err := p.lookupAll(context.Background(), p.networks()...)
+	if errors.Is(err, errHostNoFamilyAddress) && !bool(p.IPv4Only) && !bool(p.IPv6Only) {
+		// resolve in any network, where the address is in
+		// another family than the source address (see Validate)
+		addrs, err = p.lookupAll(context.Background(), ipv4Network, ipv6Network)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return addrs[0], addrs[0].IP.To4() != nil, nil
+}
+
+// gets the resolver looking up the host and the
+// names of reply addresses, the system's if unset
+func (p *Ping) resolver() Resolver {
+	if p.Resolver == nil {
+		return net.DefaultResolver
+	}
+	return p.Resolver
+}
+
+// resolves the host name into all of its addresses in the
+// networks of the Ping, ordered by network
+func (p *Ping) resolveAll() ([]*net.IPAddr, error) {
+	return p.lookupAll(context.Background(), p.networks()...)
+}
+
+// looks up all the addresses of the host name in the
+// networks (ip4, ip6) with the resolver, ordered by
+// network, until the context is done
+func (p *Ping) lookupAll(ctx context.Context, networks ...string) ([]*net.IPAddr, error) {
+	found, err := p.resolver().LookupIPAddr(ctx, p.HostName)
+	if err != nil {
+		return nil, &ResolveError{Host: p.HostName, Err: err}
+	}
+	var addrs []*net.IPAddr
+	for _, network := range networks {
+		for i := range found {
+			if (found[i].IP.To4() != nil) == (network == ipv4Network) {
+				addrs = append(addrs, &found[i])
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, &ResolveError{Host: p.HostName, Err: errHostNoFamilyAddress}
+	}
+	return addrs, nil
+}
+
+// reports if the datagram quoted by an error reply was sent to
+// the host, so errors about other pings' packets are ignored,
+// where header is either a *ipv4.Header or *ipv6.Header
+func (p *Ping) isHostDst(header interface{}) bool {
+	var dst net.IP
+	switch header := header.(type) {
+	case *ipv4.Header:
+		dst = header.Dst
+	case *ipv6.Header:
+		dst = header.Dst
+	default:
+		return false
+	}
+	return p.isHostIP(dst)
+}
+
+// reports if an address reaches a group of hosts, either as a
+// multicast address or as the limited or directed broadcast
+// address of one of the system's networks
+func isGroupAddress(ip net.IP) bool {
+	if ip.IsMulticast() || ip.Equal(net.IPv4bcast) {
+		return true
+	}
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return false // ipv6 has no broadcast
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil || len(ipNet.Mask) != net.IPv4len {
+			continue
+		}
+		// directed broadcast has all host bits set
+		broadcast := make(net.IP, net.IPv4len)
+		for i := range broadcast {
+			broadcast[i] = ipNet.IP.To4()[i] | ^ipNet.Mask[i]
+		}
+		if ip4.Equal(broadcast) {
+			return true
+		}
+	}
+	return false
+}
+
+// reports if a reply from the peer can be a reply from the host,
+// where replies to a group of hosts may come from any of them
+func (p *Ping) isHostPeer(peer net.Addr) bool {
+	ip, _ := peerIP(peer)
+	if ip == nil {
+		return false
+	}
+	return p.isHostIP(ip)
+}
+
+// reports if an ip is an address the host was pinged at, which
+// are all the addresses it resolved to during the run, so replies
+// in flight when it changed address are still accepted
+func (p *Ping) isHostIP(ip net.IP) bool {
+	if p.isGroupHost {
+		return true
+	}
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	for _, segment := range p.segments {
+		if ip.Equal(segment.addr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// gets the ip address and zone of a peer,
+// or a nil ip if it is not an ip or udp address
+func peerIP(peer net.Addr) (net.IP, string) {
+	switch addr := peer.(type) {
+	case *net.IPAddr:
+		return addr.IP, addr.Zone
+	case *net.UDPAddr:
+		return addr.IP, addr.Zone
+	default:
+		return nil, ""
+	}
 }
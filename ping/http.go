@@ -0,0 +1,197 @@
+package ping
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// constants for HTTP probes
+	httpMethod = http.MethodGet
+	httpPath   = "/"
+)
+
+// represents the time taken by each phase of an HTTP probe,
+// where phases that did not happen (ex. tls for http) are 0
+type httpPhases struct {
+	dns       time.Duration // resolving the host name
+	connect   time.Duration // tcp handshake
+	tls       time.Duration // tls handshake
+	firstByte time.Duration // from sending until the first response byte
+}
+
+// initializes the Ping's private fields for HTTP probes
+func (p *Ping) initHTTP() error {
+	port := strconv.Itoa(int(p.Probe.Port))
+	p.httpURL = &url.URL{
+		Scheme: p.Probe.Protocol,
+		Host:   net.JoinHostPort(p.HostName, port),
+		Path:   httpPath,
+	}
+	return nil
+}
+
+// sends an HTTP "echo request" to a host for a particular
+// sequence using the Ping request, where the reply is
+// the response to a request for the root path
+func (p *Ping) sendHTTP(seq uint64) error {
+	packet := &icmpPacket{seq: seq}
+	p.sentMux.Lock()
+	p.addSent(packet)
+	p.sentMux.Unlock()
+	p.waitGroup.Add(1)
+	go p.request(seq, packet)
+	return nil
+}
+
+// performs the request for a sent HTTP probe, timing each phase
+func (p *Ping) request(seq uint64, packet *icmpPacket) {
+	defer p.waitGroup.Done()
+	var phases httpPhases
+	var phasesMux sync.Mutex // the dial and trace hooks may be called concurrently
+	dial := func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, dns, err := p.dialHTTP(ctx, address)
+		phasesMux.Lock()
+		defer phasesMux.Unlock()
+		phases.dns = dns
+		return conn, err
+	}
+	// use a new connection for each request, so every phase is timed
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext:       dial,
+			DisableKeepAlives: true,
+			TLSClientConfig:   p.tlsConfig(),
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse // time the host itself, not redirects
+		},
+		Timeout: p.probeTimeout(), // give up once the wait time is exceeded
+	}
+	var connectStart, tlsStart, wroteRequest time.Time
+	start := func(t *time.Time) {
+		phasesMux.Lock()
+		defer phasesMux.Unlock()
+		*t = time.Now()
+	}
+	done := func(phase *time.Duration, t *time.Time) {
+		phasesMux.Lock()
+		defer phasesMux.Unlock()
+		*phase = time.Since(*t)
+	}
+	trace := &httptrace.ClientTrace{
+		ConnectStart:         func(string, string) { start(&connectStart) },
+		ConnectDone:          func(string, string, error) { done(&phases.connect, &connectStart) },
+		TLSHandshakeStart:    func() { start(&tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { done(&phases.tls, &tlsStart) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { start(&wroteRequest) },
+		GotFirstResponseByte: func() { done(&phases.firstByte, &wroteRequest) },
+	}
+	ctx := httptrace.WithClientTrace(p.ctx, trace)
+	req, err := http.NewRequestWithContext(ctx, httpMethod, p.httpURL.String(), nil)
+	if err != nil {
+		p.sentMux.Lock()
+		p.markExpired(packet) // no response from the host
+		p.sentMux.Unlock()
+		p.printReply("%v: http_seq=%v error=%v\n", p.httpURL.Host, seq, err)
+		return
+	}
+	p.sentMux.Lock()
+	packet.sendTime = time.Now()
+	p.sentMux.Unlock()
+	resp, err := client.Do(req)
+	var n int64
+	if err == nil {
+		// the request is only done once the body is read
+		n, err = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	recvTime := time.Now()
+	if p.ctx.Err() != nil {
+		return // ping is stopping, so ignore the result
+	}
+	if err != nil {
+		p.sentMux.Lock()
+		p.markExpired(packet) // no response from the host
+		p.sentMux.Unlock()
+		p.printReply("%v: http_seq=%v error=%v\n", p.httpURL.Host, seq, err)
+		return
+	}
+	phasesMux.Lock()
+	result := phases
+	phasesMux.Unlock()
+	p.sentMux.Lock()
+	p.markReceived(packet, recvTime, false)
+	rtt := packet.roundtripTime
+	p.httpPhases.dns += result.dns
+	p.httpPhases.connect += result.connect
+	p.httpPhases.tls += result.tls
+	p.httpPhases.firstByte += result.firstByte
+	p.sentMux.Unlock()
+	p.printReply("%v bytes from %v: http_seq=%v status=%v dns=%v connect=%v tls=%v ttfb=%v time=%v\n",
+		n, p.httpURL.Host, seq, resp.StatusCode, result.dns, result.connect, result.tls, result.firstByte, rtt)
+}
+
+// gets the tls config of an HTTPS probe, verifying the
+// certificate of the host name unless set otherwise
+func (p *Ping) tlsConfig() *tls.Config {
+	if p.TLSConfig == nil {
+		return &tls.Config{ServerName: p.HostName}
+	}
+	config := p.TLSConfig.Clone()
+	if config.ServerName == "" {
+		config.ServerName = p.HostName
+	}
+	return config
+}
+
+// dials the address of the host for an HTTP probe, which is the
+// address it resolved to at the start or when re-resolved, so the
+// probe reaches the address its statistics are kept for, while the
+// host name is still used for the request and tls, returning the
+// time taken to look the host name up again for the dns phase
+func (p *Ping) dialHTTP(ctx context.Context, address string) (net.Conn, time.Duration, error) {
+	_, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, 0, err
+	}
+	var dns time.Duration
+	if p.hostOverride == nil && net.ParseIP(p.HostName) == nil {
+		network := ipv6Network
+		if p.isIPv4 {
+			network = ipv4Network
+		}
+		start := time.Now()
+		_, err = p.lookupAll(ctx, network)
+		dns = time.Since(start)
+		if err != nil {
+			return nil, dns, err
+		}
+	}
+	p.sentMux.Lock()
+	host := p.hostAddr
+	p.sentMux.Unlock()
+	conn, err := p.probeDialer().DialContext(ctx, tcpNetwork, net.JoinHostPort(host.String(), port))
+	return conn, dns, err
+}
+
+// prints the average time of each phase of the received
+// HTTP probes, must be called with sentMux held
+func (p *Ping) printHTTPStats() {
+	received, sum := p.stats.received, p.httpPhases
+	if received == 0 {
+		return // no phases to average
+	}
+	fmt.Fprintf(p.output(), "phases avg dns/connect/tls/ttfb = %v/%v/%v/%v\n",
+		sum.dns/time.Duration(received), sum.connect/time.Duration(received),
+		sum.tls/time.Duration(received), sum.firstByte/time.Duration(received))
+}
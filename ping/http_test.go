@@ -0,0 +1,134 @@
+package ping
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// matches the reply to an http probe, capturing the phases
+var httpReplyPattern = regexp.MustCompile(
+	`bytes from \S+: http_seq=0 status=(\d+) dns=(\S+) connect=(\S+) tls=(\S+) ttfb=(\S+) time=(\S+)`)
+
+// runs a probe of a test server, returning the phases of its reply
+func runHTTPProbe(t *testing.T, server *httptest.Server, protocol string, opts ...Option) map[string]time.Duration {
+	t.Helper()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse %v: %v", server.URL, err)
+	}
+	opts = append(opts, WithProbe(protocol+":"+serverURL.Port()))
+	output := runProbe(t, serverURL.Hostname(), opts...)
+	match := httpReplyPattern.FindStringSubmatch(output)
+	if match == nil {
+		t.Fatalf("expected a reply, got:\n%v", output)
+	}
+	if match[1] != "200" {
+		t.Errorf("expected status 200, got %v", match[1])
+	}
+	phases := make(map[string]time.Duration)
+	for i, phase := range []string{"dns", "connect", "tls", "ttfb", "time"} {
+		d, err := time.ParseDuration(match[i+2])
+		if err != nil {
+			t.Fatalf("failed to parse %v=%v: %v", phase, match[i+2], err)
+		}
+		phases[phase] = d
+	}
+	if !regexp.MustCompile(`phases avg dns/connect/tls/ttfb = \S+/\S+/\S+/\S+`).MatchString(output) {
+		t.Errorf("expected the average phases, got:\n%v", output)
+	}
+	return phases
+}
+
+// handles requests of the test servers
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok"))
+}
+
+func TestHTTPProbePhases(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+	phases := runHTTPProbe(t, server, probeHTTP)
+	if phases["dns"] != 0 {
+		t.Errorf("expected no dns phase for an address, got %v", phases["dns"])
+	}
+	if phases["connect"] <= 0 || phases["ttfb"] <= 0 {
+		t.Errorf("expected connect and ttfb phases, got %v", phases)
+	}
+	if phases["tls"] != 0 {
+		t.Errorf("expected no tls phase for http, got %v", phases["tls"])
+	}
+	if phases["time"] < phases["connect"]+phases["ttfb"] {
+		t.Errorf("expected the time to include the phases, got %v", phases)
+	}
+}
+
+func TestHTTPSProbePhases(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+	config := server.Client().Transport.(*http.Transport).TLSClientConfig
+	phases := runHTTPProbe(t, server, probeHTTPS, WithTLSConfig(config))
+	if phases["connect"] <= 0 || phases["tls"] <= 0 || phases["ttfb"] <= 0 {
+		t.Errorf("expected connect, tls and ttfb phases, got %v", phases)
+	}
+	if phases["time"] < phases["connect"]+phases["tls"]+phases["ttfb"] {
+		t.Errorf("expected the time to include the phases, got %v", phases)
+	}
+}
+
+func TestHTTPSProbeUntrusted(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // the handshake is expected to fail
+	server.StartTLS()
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse %v: %v", server.URL, err)
+	}
+	output := runProbe(t, serverURL.Hostname(), WithProbe(probeHTTPS+":"+serverURL.Port()))
+	if !regexp.MustCompile(`http_seq=0 error=.*certificate`).MatchString(output) {
+		t.Errorf("expected a certificate error, got:\n%v", output)
+	}
+}
+
+func TestHTTPProbeFollowsReresolve(t *testing.T) {
+	hosts := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts <- r.Host
+		okHandler(w, r)
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("failed to parse %v: %v", server.URL, err)
+	}
+	// the host first resolves to an address the server is not listening at
+	resolver := &staticResolver{}
+	resolver.setAddrs("127.0.0.2")
+	var output bytes.Buffer
+	p := newResolvedPing(t, resolver, &output, WithProbe(probeHTTP+":"+serverURL.Port()), WithReresolve(time.Hour))
+	p.sendHTTP(0)
+	p.waitGroup.Wait()
+	resolver.setAddrs("127.0.0.1")
+	p.reresolve()
+	p.sendHTTP(1)
+	p.waitGroup.Wait()
+	if !regexp.MustCompile(`bytes from ping\.test:\d+: http_seq=1 status=200`).MatchString(output.String()) {
+		t.Fatalf("expected a reply from the new address, got:\n%v", output.String())
+	}
+	if host := <-hosts; host != "ping.test:"+serverURL.Port() {
+		t.Errorf("expected the request for the host name, got %v", host)
+	}
+	if len(hosts) != 0 {
+		t.Errorf("expected the first probe to miss the server at the old address")
+	}
+	if got := p.segments[1].stats; got.transmitted != 1 || got.received != 1 {
+		t.Errorf("expected 1/1 packets at the new address, got %v/%v", got.received, got.transmitted)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package ping
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	// signals that print interim stats without stopping, where
+	// SIGINFO is sent by the terminal on ctrl-t like in BSD ping
+	infoSignals = []os.Signal{syscall.SIGINFO, syscall.SIGQUIT}
+)
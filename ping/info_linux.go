@@ -0,0 +1,15 @@
+//go:build linux
+// +build linux
+
+package ping
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	// signals that print interim stats without stopping, where
+	// SIGQUIT is sent by the terminal on ctrl-\ like in iputils ping
+	infoSignals = []os.Signal{syscall.SIGQUIT}
+)
@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !linux
+// +build !darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!linux
+
+package ping
+
+import (
+	"os"
+)
+
+var (
+	// interim stats are not supported on this system,
+	// so there are no signals to print them
+	infoSignals []os.Signal
+)
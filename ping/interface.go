@@ -0,0 +1,66 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	// Interface constants based off the man page for 'ping'.
+	interfaceFlag = "I"
+	interfaceHelp = "Set the interface outgoing packets are sent from by name\n" +
+		"(ex. eth0), where replies are only accepted if they arrive on it.\n" +
+		"If unset, the interface is chosen by the system, or by the zone\n" +
+		"of a link-local IPv6 host (ex. fe80::1%eth0)."
+	interfaceInvalid         = "interface must be the name of a network interface"
+	interfaceMismatch        = "interface does not match the zone of the host"
+	interfaceBindUnsupported = "binding to an interface is not supported on this system"
+)
+
+var (
+	// errors for invalid interface
+	errInterfaceInvalid         = errors.New(interfaceInvalid)
+	errInterfaceMismatch        = errors.New(interfaceMismatch)
+	errInterfaceBindUnsupported = errors.New(interfaceBindUnsupported)
+)
+
+// Interface is a wrapper around a boolean and a network
+// interface name to use for command-line argument flag parsing.
+type Interface struct {
+	IsSet bool
+	Value string
+}
+
+// Init initializes an Interface instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Interface) Init() {
+}
+
+// String is used to format Interface's value and is required
+// to satisfy the flag.Value interface.
+func (i *Interface) String() string {
+	return fmt.Sprintf("set=%v, value=%v", i.IsSet, i.Value)
+}
+
+// Set will initialize Interface's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (i *Interface) Set(val string) error {
+	if _, err := net.InterfaceByName(val); err != nil {
+		return errInterfaceInvalid
+	}
+	i.IsSet = true
+	i.Value = val
+	return nil
+}
+
+// Flag gets the command-line flag used for Interface.
+func (*Interface) Flag() string {
+	return interfaceFlag
+}
+
+// Help gets the command-line help for Interface.
+func (*Interface) Help() string {
+	return interfaceHelp
+}
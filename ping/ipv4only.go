@@ -0,0 +1,59 @@
+package ping
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// IPv4Only constants based off the man page for iputils 'ping'.
+	ipv4OnlyFlag = "4"
+	ipv4OnlyHelp = "Use IPv4 only. The host name is only resolved to its\n" +
+		"IPv4 addresses. If unset, the addresses are of the family\n" +
+		"of the source address (-S) if set, or else IPv4 if the host\n" +
+		"has any, and IPv6 otherwise. This flag (-4) is incompatible\n" +
+		"with -6."
+)
+
+// IPv4Only is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type IPv4Only bool
+
+// Init initializes an IPv4Only instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*IPv4Only) Init() {
+}
+
+// String is used to format IPv4Only's value and is required
+// to satisfy the flag.Value interface.
+func (o *IPv4Only) String() string {
+	return fmt.Sprintf("value=%v", *o)
+}
+
+// Set will initialize IPv4Only's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (o *IPv4Only) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*o = IPv4Only(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for IPv4Only.
+func (*IPv4Only) Flag() string {
+	return ipv4OnlyFlag
+}
+
+// Help gets the command-line help for IPv4Only.
+func (*IPv4Only) Help() string {
+	return ipv4OnlyHelp
+}
+
+// IsBoolFlag is used to notify that IPv4Only is
+// a boolean flag, so '-4' defaults to '-4=true' or '-4 true'.
+func (*IPv4Only) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,59 @@
+package ping
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// IPv6Only constants based off the man page for iputils 'ping'.
+	ipv6OnlyFlag = "6"
+	ipv6OnlyHelp = "Use IPv6 only. The host name is only resolved to its\n" +
+		"IPv6 addresses. If unset, the addresses are of the family\n" +
+		"of the source address (-S) if set, or else IPv4 if the host\n" +
+		"has any, and IPv6 otherwise. This flag (-6) is incompatible\n" +
+		"with -4."
+)
+
+// IPv6Only is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type IPv6Only bool
+
+// Init initializes an IPv6Only instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*IPv6Only) Init() {
+}
+
+// String is used to format IPv6Only's value and is required
+// to satisfy the flag.Value interface.
+func (o *IPv6Only) String() string {
+	return fmt.Sprintf("value=%v", *o)
+}
+
+// Set will initialize IPv6Only's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (o *IPv6Only) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*o = IPv6Only(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for IPv6Only.
+func (*IPv6Only) Flag() string {
+	return ipv6OnlyFlag
+}
+
+// Help gets the command-line help for IPv6Only.
+func (*IPv6Only) Help() string {
+	return ipv6OnlyHelp
+}
+
+// IsBoolFlag is used to notify that IPv6Only is
+// a boolean flag, so '-6' defaults to '-6=true' or '-6 true'.
+func (*IPv6Only) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,64 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// Loss constants for the responder.
+	lossFlag = "L"
+	lossHelp = "Set the percentage of requests to drop without replying.\n" +
+		"The number can be a fraction (ex. 0.5). If unset, no requests\n" +
+		"are dropped."
+	lossInvalid      = "loss must be between 0 and 100"
+	lossInputBitSize = 64 // float64 accepted as input, so need 64 bits
+	lossMax          = 100
+)
+
+var (
+	// error for invalid loss
+	errLossInvalid = errors.New(lossInvalid)
+)
+
+// Loss is a wrapper around a percentage
+// to use for command-line argument flag parsing.
+type Loss float64
+
+// Init initializes a Loss instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Loss) Init() {
+}
+
+// String is used to format Loss's value and is required
+// to satisfy the flag.Value interface.
+func (l *Loss) String() string {
+	return fmt.Sprintf("value=%v%%", float64(*l))
+}
+
+// Set will initialize Loss's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (l *Loss) Set(val string) error {
+	res, err := strconv.ParseFloat(val, lossInputBitSize)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(res) || res < 0 || res > lossMax {
+		return errLossInvalid
+	}
+	*l = Loss(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Loss.
+func (*Loss) Flag() string {
+	return lossFlag
+}
+
+// Help gets the command-line help for Loss.
+func (*Loss) Help() string {
+	return lossHelp
+}
@@ -0,0 +1,68 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxLoss constants for the success criteria of a run.
+	maxLossFlag = "max-loss"
+	maxLossHelp = "Set the max packet loss in percent (ex. 5 or 0.5%) for the\n" +
+		"run to succeed. If the loss is higher, the program exits with\n" +
+		"status 1. If unset, any loss succeeds as long as a reply arrives."
+	maxLossInvalid = "max loss must be from 0 to 100 percent"
+	percentSuffix  = "%"
+)
+
+var (
+	// error for invalid max loss
+	errMaxLossInvalid = errors.New(maxLossInvalid)
+)
+
+// MaxLoss is a wrapper around a boolean and a float
+// to use for command-line argument flag parsing.
+type MaxLoss struct {
+	IsSet bool
+	Value float64
+}
+
+// Init initializes a MaxLoss instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*MaxLoss) Init() {
+}
+
+// String is used to format MaxLoss's value and is required
+// to satisfy the flag.Value interface.
+func (m *MaxLoss) String() string {
+	return fmt.Sprintf("set=%v, value=%v%%", m.IsSet, m.Value)
+}
+
+// Set will initialize MaxLoss's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (m *MaxLoss) Set(val string) error {
+	res, err := strconv.ParseFloat(strings.TrimSuffix(val, percentSuffix), 64)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(res) || res < 0 || res > 100 {
+		return errMaxLossInvalid
+	}
+	m.IsSet = true
+	m.Value = res
+	return nil
+}
+
+// Flag gets the command-line flag used for MaxLoss.
+func (*MaxLoss) Flag() string {
+	return maxLossFlag
+}
+
+// Help gets the command-line help for MaxLoss.
+func (*MaxLoss) Help() string {
+	return maxLossHelp
+}
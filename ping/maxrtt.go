@@ -0,0 +1,73 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	// MaxRTT constants for the success criteria of a run.
+	maxRTTFlag = "max-rtt"
+	maxRTTHelp = "Set the max average round-trip time in milliseconds (ex. 20\n" +
+		"or 0.5) for the run to succeed. If the average is higher, the\n" +
+		"program exits with status 1. If unset, any round-trip time\n" +
+		"succeeds as long as a reply arrives."
+	maxRTTInvalid = "max round-trip time must be a number of milliseconds greater than 0"
+)
+
+var (
+	// error for invalid max round-trip time
+	errMaxRTTInvalid = errors.New(maxRTTInvalid)
+)
+
+// MaxRTT is a wrapper around a boolean and a time.Duration
+// to use for command-line argument flag parsing.
+type MaxRTT struct {
+	IsSet bool
+	Value time.Duration
+}
+
+// Init initializes a MaxRTT instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*MaxRTT) Init() {
+}
+
+// String is used to format MaxRTT's value and is required
+// to satisfy the flag.Value interface.
+func (m *MaxRTT) String() string {
+	return fmt.Sprintf("set=%v, value=%v", m.IsSet, m.Value)
+}
+
+// Set will initialize MaxRTT's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (m *MaxRTT) Set(val string) error {
+	res, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return err
+	}
+	// a NaN fails every comparison, so check the range it is in
+	if !(res > 0 && res <= float64(math.MaxInt64)/float64(time.Millisecond)) {
+		return errMaxRTTInvalid
+	}
+	rtt := time.Duration(res * float64(time.Millisecond))
+	if rtt <= 0 {
+		return errMaxRTTInvalid // less than a nanosecond
+	}
+	m.IsSet = true
+	m.Value = rtt
+	return nil
+}
+
+// Flag gets the command-line flag used for MaxRTT.
+func (*MaxRTT) Flag() string {
+	return maxRTTFlag
+}
+
+// Help gets the command-line help for MaxRTT.
+func (*MaxRTT) Help() string {
+	return maxRTTHelp
+}
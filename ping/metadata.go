@@ -0,0 +1,61 @@
+package ping
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"time"
+)
+
+const (
+	// layout of the metadata at the start of an echo request payload,
+	// after the session's cookie, which is only written if the packet
+	// size leaves room for all of it
+	metadataSeqOffset      = sessionCookieSize     // sequence (uint64)
+	metadataTimeOffset     = metadataSeqOffset + 8 // send time in unix nanoseconds (int64)
+	metadataChecksumOffset = metadataTimeOffset + 8
+	metadataSize           = metadataChecksumOffset + crc32.Size // crc32 of the rest of the payload
+)
+
+// represents the metadata embedded in an echo request payload,
+// like the timestamp iputils embeds, so the round-trip time of
+// a reply can be taken and its payload checked from the reply
+// itself, without the request it answers
+type metadata struct {
+	seq      uint64    // sequence sent
+	sendTime time.Time // time sent
+	intact   bool      // if the checksum matches the payload
+}
+
+// writes the metadata of a sequence sent at a time into a payload,
+// returning false if the payload is too small to hold it, where the
+// checksum is over the whole payload but the checksum itself, so it
+// must be written once the rest of the payload is final
+func writeMetadata(payload []byte, seq uint64, sendTime time.Time) bool {
+	if len(payload) < metadataSize {
+		return false
+	}
+	binary.BigEndian.PutUint64(payload[metadataSeqOffset:], seq)
+	binary.BigEndian.PutUint64(payload[metadataTimeOffset:], uint64(sendTime.UnixNano()))
+	binary.BigEndian.PutUint32(payload[metadataChecksumOffset:], payloadChecksum(payload))
+	return true
+}
+
+// parses the metadata of a payload, returning false if
+// the payload is too small to hold it
+func parseMetadata(payload []byte) (metadata, bool) {
+	if len(payload) < metadataSize {
+		return metadata{}, false
+	}
+	return metadata{
+		seq:      binary.BigEndian.Uint64(payload[metadataSeqOffset:]),
+		sendTime: time.Unix(0, int64(binary.BigEndian.Uint64(payload[metadataTimeOffset:]))),
+		intact:   binary.BigEndian.Uint32(payload[metadataChecksumOffset:]) == payloadChecksum(payload),
+	}, true
+}
+
+// computes the checksum of a payload, which covers
+// every byte of the payload but the checksum itself
+func payloadChecksum(payload []byte) uint32 {
+	checksum := crc32.ChecksumIEEE(payload[:metadataChecksumOffset])
+	return crc32.Update(checksum, crc32.IEEETable, payload[metadataSize:])
+}
@@ -0,0 +1,94 @@
+package ping
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	reverseLookupTimeout = 5 * time.Second // max time to look up the name of an address
+)
+
+// Resolver looks up the addresses of the host and the names of
+// the addresses replies come from, which is satisfied by
+// *net.Resolver, and can be replaced with a fake to test
+// the output without a network.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// caches the names of addresses, which are looked up in
+// the background, so outputting a reply never waits for them
+type nameCache struct {
+	resolver Resolver
+	names    map[string]string // names by address, empty while looked up or if none
+	mux      sync.Mutex
+}
+
+// creates a cache of the names looked up with the resolver
+func newNameCache(resolver Resolver) *nameCache {
+	return &nameCache{
+		resolver: resolver,
+		names:    make(map[string]string),
+	}
+}
+
+// gets the cached name of an address, reporting if it is looked
+// up yet, where the first call for an address starts the lookup
+func (c *nameCache) get(addr string) (name string, cached bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	name, cached = c.names[addr]
+	if !cached {
+		c.names[addr] = "" // only look up each address once
+	}
+	return name, cached
+}
+
+// sets the name of an address once looked up
+func (c *nameCache) set(addr, name string) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.names[addr] = name
+}
+
+// looks up the name of an address in the background,
+// until the ping stops or the lookup times out
+func (p *Ping) lookupName(addr string) {
+	defer p.waitGroup.Done()
+	ctx, cancel := context.WithTimeout(p.ctx, reverseLookupTimeout)
+	defer cancel()
+	names, err := p.names.resolver.LookupAddr(ctx, addr)
+	if err != nil || len(names) == 0 {
+		return // no name, so the address is output on its own
+	}
+	p.names.set(addr, strings.TrimSuffix(names[0], "."))
+}
+
+// describes the address a reply came from for the output, along
+// with its name once looked up, unless the output is numeric
+func (p *Ping) describePeer(peer net.Addr) string {
+	ip, zone := peerIP(peer)
+	if ip == nil {
+		return peer.String() // not an ip address, so nothing to look up
+	}
+	addr := (&net.IPAddr{IP: ip, Zone: zone}).String()
+	if bool(p.Numeric) {
+		return addr
+	}
+	name, cached := p.names.get(ip.String())
+	if !cached {
+		// look up in the background, so later replies have the name
+		p.waitGroup.Add(1)
+		go p.lookupName(ip.String())
+	}
+	if name == "" {
+		return addr
+	}
+	return fmt.Sprintf("%v (%v)", name, addr)
+}
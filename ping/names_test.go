@@ -0,0 +1,97 @@
+package ping
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+)
+
+// represents a resolver whose lookups block until released,
+// counting the lookups of each address
+type blockingResolver struct {
+	release chan struct{}     // closed to let the lookups return
+	lookups map[string]int    // lookups by address
+	names   map[string]string // names by address
+	mux     sync.Mutex
+}
+
+func (r *blockingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return net.DefaultResolver.LookupIPAddr(ctx, host)
+}
+
+func (r *blockingResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.mux.Lock()
+	r.lookups[addr]++
+	r.mux.Unlock()
+	select {
+	case <-r.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if name, ok := r.names[addr]; ok {
+		return []string{name + "."}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+// creates a Ping whose names are looked up with the resolver
+func newNamesPing(resolver Resolver) *Ping {
+	return &Ping{ctx: context.Background(), names: newNameCache(resolver)}
+}
+
+func TestDescribePeerDoesNotWait(t *testing.T) {
+	resolver := &blockingResolver{
+		release: make(chan struct{}),
+		lookups: make(map[string]int),
+		names:   map[string]string{"192.0.2.1": "router.example"},
+	}
+	p := newNamesPing(resolver)
+	peer := &net.IPAddr{IP: net.ParseIP("192.0.2.1")}
+	// the lookup blocks, so the address is output on its own
+	for i := 0; i < 3; i++ {
+		if got := p.describePeer(peer); got != "192.0.2.1" {
+			t.Errorf("expected the bare address while looked up, got %q", got)
+		}
+	}
+	close(resolver.release)
+	p.waitGroup.Wait()
+	if got := p.describePeer(peer); got != "router.example (192.0.2.1)" {
+		t.Errorf("expected the name once looked up, got %q", got)
+	}
+	if lookups := resolver.lookups["192.0.2.1"]; lookups != 1 {
+		t.Errorf("expected 1 lookup of the address, got %v", lookups)
+	}
+}
+
+func TestDescribePeerWithoutName(t *testing.T) {
+	resolver := &blockingResolver{
+		release: make(chan struct{}),
+		lookups: make(map[string]int),
+	}
+	close(resolver.release)
+	p := newNamesPing(resolver)
+	peer := &net.IPAddr{IP: net.ParseIP("2001:db8::1")}
+	p.describePeer(peer)
+	p.waitGroup.Wait()
+	// a failed lookup is cached too, so it is never retried
+	if got := p.describePeer(peer); got != "2001:db8::1" {
+		t.Errorf("expected the bare address without a name, got %q", got)
+	}
+	if lookups := resolver.lookups["2001:db8::1"]; lookups != 1 {
+		t.Errorf("expected 1 lookup of the address, got %v", lookups)
+	}
+}
+
+func TestDescribePeerNumeric(t *testing.T) {
+	resolver := &blockingResolver{lookups: make(map[string]int)}
+	p := newNamesPing(resolver)
+	p.Numeric = true
+	peer := &net.IPAddr{IP: net.ParseIP("192.0.2.1")}
+	if got := p.describePeer(peer); got != "192.0.2.1" {
+		t.Errorf("expected the bare address, got %q", got)
+	}
+	if len(resolver.lookups) != 0 {
+		t.Errorf("expected no lookups, got %v", resolver.lookups)
+	}
+}
@@ -0,0 +1,58 @@
+package ping
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// Numeric constants based off the man page for 'ping'.
+	numericFlag = "n"
+	numericHelp = "Set the output to numeric only. No attempt is made to\n" +
+		"look up the names of the addresses replies come from. If\n" +
+		"unset, the names are looked up in the background and output\n" +
+		"once known, without delaying the replies."
+)
+
+// Numeric is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type Numeric bool
+
+// Init initializes a Numeric instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Numeric) Init() {
+}
+
+// String is used to format Numeric's value and is required
+// to satisfy the flag.Value interface.
+func (n *Numeric) String() string {
+	return fmt.Sprintf("value=%v", *n)
+}
+
+// Set will initialize Numeric's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (n *Numeric) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*n = Numeric(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Numeric.
+func (*Numeric) Flag() string {
+	return numericFlag
+}
+
+// Help gets the command-line help for Numeric.
+func (*Numeric) Help() string {
+	return numericHelp
+}
+
+// IsBoolFlag is used to notify that Numeric is
+// a boolean flag, so '-n' defaults to '-n=true' or '-n true'.
+func (*Numeric) IsBoolFlag() bool {
+	return true
+}
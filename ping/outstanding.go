@@ -0,0 +1,90 @@
+package ping
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// Outstanding constants based off the man page for iputils 'ping'.
+	outstandingFlag = "O"
+	outstandingHelp = "Report outstanding replies. A line is output for each packet\n" +
+		"that has no reply once its wait time (-W) passed, so gaps are\n" +
+		"visible as they happen. If unset, missing replies are only\n" +
+		"counted in the summary. Late replies are output either way,\n" +
+		"marked with how long after the wait time they arrived."
+)
+
+// Outstanding is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type Outstanding bool
+
+// Init initializes an Outstanding instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Outstanding) Init() {
+}
+
+// String is used to format Outstanding's value and is required
+// to satisfy the flag.Value interface.
+func (o *Outstanding) String() string {
+	return fmt.Sprintf("value=%v", *o)
+}
+
+// Set will initialize Outstanding's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (o *Outstanding) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*o = Outstanding(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Outstanding.
+func (*Outstanding) Flag() string {
+	return outstandingFlag
+}
+
+// Help gets the command-line help for Outstanding.
+func (*Outstanding) Help() string {
+	return outstandingHelp
+}
+
+// IsBoolFlag is used to notify that Outstanding is
+// a boolean flag, so '-O' defaults to '-O=true' or '-O true'.
+func (*Outstanding) IsBoolFlag() bool {
+	return true
+}
+
+// prints that a sent sequence has no reply within its
+// wait time, if reporting outstanding replies
+func (p *Ping) printOutstanding(seq uint64) {
+	if !bool(p.Outstanding) {
+		return
+	}
+	p.printReply("no answer yet for %v=%v\n", p.seqLabel(), seq)
+}
+
+// gets the label of the sequence in the output of a reply
+func (p *Ping) seqLabel() string {
+	if p.Probe.Protocol == probeUDP {
+		return "udp_seq"
+	}
+	return "icmp_seq"
+}
+
+// describes how long after its wait time a late reply
+// arrived, or nothing if the reply was in time
+func (p *Ping) describeLate(packet *icmpPacket) string {
+	if !packet.waitTimeExceeded {
+		return ""
+	}
+	late := packet.roundtripTime - time.Duration(p.WaitTime)
+	if late < 0 {
+		late = 0 // marked late just before its wait time
+	}
+	return fmt.Sprintf(" (late, %.1fms)", float64(late)/float64(time.Millisecond))
+}
@@ -0,0 +1,60 @@
+package ping
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// runs 2 pings whose first reply arrives after its wait time, while
+// the second is still in flight, returning the output
+func runLateEcho(t *testing.T, opts ...Option) string {
+	t.Helper()
+	var output bytes.Buffer
+	opts = append([]Option{WithCount(2), WithInterval(150 * time.Millisecond),
+		WithWaitTime(30 * time.Millisecond), WithOutput(&output)}, opts...)
+	runEcho(t, 100*time.Millisecond, opts...)
+	return output.String()
+}
+
+func TestOutstandingReported(t *testing.T) {
+	output := runLateEcho(t, WithOutstanding())
+	for _, seq := range []string{"0", "1"} {
+		if !regexp.MustCompile(`no answer yet for icmp_seq=` + seq + `\n`).MatchString(output) {
+			t.Errorf("expected icmp_seq=%v to be outstanding, got:\n%v", seq, output)
+		}
+	}
+	if !regexp.MustCompile(`icmp_seq=0 .*time=\S+ \(late, \S+ms\)\n`).MatchString(output) {
+		t.Errorf("expected a late reply to icmp_seq=0, got:\n%v", output)
+	}
+}
+
+func TestOutstandingNotReported(t *testing.T) {
+	// late replies are still printed without -O
+	output := runLateEcho(t)
+	if regexp.MustCompile(`no answer yet`).MatchString(output) {
+		t.Errorf("expected no outstanding lines, got:\n%v", output)
+	}
+	if !regexp.MustCompile(`icmp_seq=0 .*\(late, \S+ms\)\n`).MatchString(output) {
+		t.Errorf("expected a late reply to icmp_seq=0, got:\n%v", output)
+	}
+}
+
+func TestDescribeLate(t *testing.T) {
+	p := &Ping{Config: DefaultConfig("127.0.0.1")}
+	p.WaitTime = WaitTime(100 * time.Millisecond)
+	tests := []struct {
+		packet icmpPacket
+		late   string
+	}{
+		{icmpPacket{roundtripTime: 50 * time.Millisecond}, ""},
+		{icmpPacket{roundtripTime: 150 * time.Millisecond, waitTimeExceeded: true}, " (late, 50.0ms)"},
+		{icmpPacket{roundtripTime: 99 * time.Millisecond, waitTimeExceeded: true}, " (late, 0.0ms)"},
+	}
+	for _, test := range tests {
+		if late := p.describeLate(&test.packet); late != test.late {
+			t.Errorf("describeLate(%v) = %q, expected %q", test.packet.roundtripTime, late, test.late)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ping
+
+import (
+	"time"
+)
+
+// paces sends as a token bucket on an absolute timebase, where
+// each send is scheduled from the timebase rather than from the
+// previous send, so the time taken to send does not make the
+// rate drift, and up to a burst of sends can be made back-to-back
+type pacer struct {
+	interval time.Duration // time between sends at the target rate
+	burst    int64         // size of the bucket
+	base     time.Time     // timebase, when the bucket was last full
+	taken    int64         // tokens taken since the timebase
+}
+
+// creates a pacer sending at an interval with a burst,
+// which starts with a full bucket
+func newPacer(interval time.Duration, burst Burst) *pacer {
+	if burst < burstDefault {
+		burst = burstDefault
+	}
+	return &pacer{
+		interval: interval,
+		burst:    int64(burst),
+		base:     time.Now(),
+	}
+}
+
+// gets the time until the next token is available
+func (pc *pacer) delay(now time.Time) time.Duration {
+	// tokens beyond the bucket size are lost, so move the timebase
+	// once more than a send behind, which tolerates sends being
+	// slightly late without losing the absolute timebase
+	if now.Sub(pc.base) > time.Duration(pc.taken+1)*pc.interval {
+		pc.base = now.Add(-time.Duration(pc.taken) * pc.interval)
+	}
+	return pc.base.Add(time.Duration(pc.taken+1-pc.burst) * pc.interval).Sub(now)
+}
+
+// takes a token, which must be available
+func (pc *pacer) take() {
+	pc.taken++
+}
+
+// waits until a token is available and takes it, returning
+// false if done was closed before, where the wait is on
+// a timer rather than spinning
+func (pc *pacer) wait(done <-chan bool) bool {
+	if delay := pc.delay(time.Now()); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-done:
+			return false
+		case <-timer.C:
+		}
+	}
+	pc.take()
+	return true
+}
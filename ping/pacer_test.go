@@ -0,0 +1,109 @@
+package ping
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// creates a pacer on a fake clock starting at a time
+func newTestPacer(interval time.Duration, burst Burst, start time.Time) *pacer {
+	pc := newPacer(interval, burst)
+	pc.base = start
+	return pc
+}
+
+// takes tokens as soon as they are available on the fake clock,
+// from a time, returning the times they were taken at
+func takeTokens(pc *pacer, now time.Time, n int) []time.Time {
+	var times []time.Time
+	for i := 0; i < n; i++ {
+		if delay := pc.delay(now); delay > 0 {
+			now = now.Add(delay)
+		}
+		pc.take()
+		times = append(times, now)
+	}
+	return times
+}
+
+func TestPacerSteadyRate(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	start := time.Unix(0, 0)
+	pc := newTestPacer(interval, 1, start)
+	for i, sent := range takeTokens(pc, start, 100) {
+		if expected := start.Add(time.Duration(i) * interval); !sent.Equal(expected) {
+			t.Fatalf("expected send %v at %v, got %v", i, expected.Sub(start), sent.Sub(start))
+		}
+	}
+	// a late send does not push back the sends after it
+	late := start.Add(100*interval + 3*time.Millisecond)
+	if delay := pc.delay(late); delay > 0 {
+		t.Fatalf("expected the late send right away, got a delay of %v", delay)
+	}
+	pc.take()
+	if delay := pc.delay(late); delay != interval-3*time.Millisecond {
+		t.Errorf("expected the next send on the timebase, %v later, got %v", interval-3*time.Millisecond, delay)
+	}
+}
+
+func TestPacerBurstAfterStall(t *testing.T) {
+	const interval, burst = 10 * time.Millisecond, 5
+	start := time.Unix(0, 0)
+	pc := newTestPacer(interval, burst, start)
+	// the bucket starts full
+	times := takeTokens(pc, start, burst+1)
+	for i := 0; i < burst; i++ {
+		if !times[i].Equal(start) {
+			t.Errorf("expected send %v of the first burst right away, got %v", i, times[i].Sub(start))
+		}
+	}
+	if gap := times[burst].Sub(start); gap != interval {
+		t.Errorf("expected the send after the burst an interval later, got %v", gap)
+	}
+	// after a stall, a burst catches up, but no more
+	stalled := start.Add(time.Second)
+	times = takeTokens(pc, stalled, burst+1)
+	for i := 0; i < burst; i++ {
+		if !times[i].Equal(stalled) {
+			t.Errorf("expected send %v of the burst after the stall right away, got %v", i, times[i].Sub(stalled))
+		}
+	}
+	if gap := times[burst].Sub(stalled); gap != interval {
+		t.Errorf("expected the send after the burst an interval later, got %v", gap)
+	}
+}
+
+func TestRatePacketsPerSecond(t *testing.T) {
+	var rate Rate
+	if err := rate.Set("10mbps"); err != nil {
+		t.Fatalf("failed to set rate: %v", err)
+	}
+	tests := []struct {
+		size PacketSize
+		pps  float64
+	}{
+		{0, 10e6 / (8 * 8)},         // icmp header only
+		{56, 10e6 / (64 * 8)},       // default packet size
+		{1472, 10e6 / (1480 * 8)},   // fills a 1500 byte ipv4 mtu
+		{65507, 10e6 / (65515 * 8)}, // max packet size
+	}
+	for _, test := range tests {
+		if pps := rate.packetsPerSecond(test.size); math.Abs(pps-test.pps) > 1e-9 {
+			t.Errorf("expected %v packets per second of %v bytes, got %v", test.pps, test.size, pps)
+		}
+	}
+	// the interval follows the packet size
+	p := &Ping{Config: DefaultConfig("127.0.0.1")}
+	p.Rate = rate
+	if interval := p.sendInterval(); interval != 51200*time.Nanosecond {
+		t.Errorf("expected an interval of 51.2µs for 56 bytes at 10mbps, got %v", interval)
+	}
+	// packets per second are used as is
+	if err := rate.Set("500"); err != nil {
+		t.Fatalf("failed to set rate: %v", err)
+	}
+	if pps := rate.packetsPerSecond(1472); pps != 500 {
+		t.Errorf("expected 500 packets per second, got %v", pps)
+	}
+}
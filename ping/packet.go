@@ -1,8 +1,12 @@
 package ping
 
 import (
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"strconv"
 	"time"
@@ -21,6 +25,8 @@ const (
 		"the minimum header size in the ipv4 library is 20."
 	packetSizeInvalid  = "packet size must be greater than or equal to 0"
 	packetSizeTooLarge = "packet size too large"
+	sessionIDSize      = 2 // bytes of the random id of a session (icmp ids are 16 bits)
+	sessionCookieSize  = 8 // bytes of the random cookie at the start of each payload
 )
 
 var (
@@ -30,13 +36,19 @@ var (
 
 // represents a sent ICMP packet
 type icmpPacket struct {
-	sendTime         time.Time     // time sent
-	receiveTime      time.Time     // time received
-	roundtripTime    time.Duration // rtt time
-	receivedTTL      int           // ttl when received
-	received         bool          // if the packet has been received
-	waitTimeExceeded bool          // if the packet exceeded its wait time
-	payload          []byte        // payload
+	seq               uint64        // sequence sent
+	sendTime          time.Time     // time sent
+	receiveTime       time.Time     // time received
+	roundtripTime     time.Duration // rtt time
+	receivedTTL       int           // ttl when received
+	receivedTOS       int           // tos when received
+	received          bool          // if the packet has been received
+	waitTimeExceeded  bool          // if the packet exceeded its wait time
+	kernelSendTime    bool          // if the send time is a kernel timestamp
+	kernelReceiveTime bool          // if the receive time is a kernel timestamp
+	payload           []byte        // payload, released once the wait time passes
+	segment           int           // index of the segment of the address it was sent to
+	period            uint64        // periodic summary it is counted in, 0 if unknown
 }
 
 // PacketSize is a wrapper around an unsigned integer
@@ -85,10 +97,53 @@ func (*PacketSize) Help() string {
 
 // GeneratePayload makes a random byte array for the packet size.
 func (p *PacketSize) GeneratePayload() []byte {
-	// init PRNG with time as seed
-	rand.Seed(time.Now().UnixNano())
 	// fill buffer with random bytes
 	buffer := make([]byte, *p)
 	rand.Read(buffer)
 	return buffer
 }
+
+// compares a received payload with the sent payload from the offset on,
+// returning the index of the first wrong byte, or -1 if they match
+func wrongByte(sent, received []byte, offset int) int {
+	for i := offset; i < len(sent) || i < len(received); i++ {
+		if i >= len(sent) || i >= len(received) || sent[i] != received[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+// prints the first wrong byte of a received payload to a writer, if any
+func printWrongByte(w io.Writer, sent, received []byte, offset int) {
+	i := wrongByte(sent, received, offset)
+	switch {
+	case i < 0:
+		return // payload is intact
+	case i >= len(sent) || i >= len(received):
+		fmt.Fprintf(w, "wrong data length: sent %v bytes but received %v\n", len(sent), len(received))
+	default:
+		fmt.Fprintf(w, "wrong data byte #%v should be 0x%x but was 0x%x\n", i, sent[i], received[i])
+	}
+}
+
+// generates a random id and cookie for a session, so replies
+// meant for another pinger on the same host are never counted
+// as ours, where the cookie is truncated to the packet size
+func (p *PacketSize) generateSession() (int, []byte, error) {
+	buffer := make([]byte, sessionIDSize+sessionCookieSize)
+	if _, err := crand.Read(buffer); err != nil {
+		return 0, nil, err
+	}
+	id := int(binary.BigEndian.Uint16(buffer))
+	cookie := buffer[sessionIDSize:]
+	if len(cookie) > int(*p) {
+		cookie = cookie[:*p]
+	}
+	return id, cookie, nil
+}
+
+// reports if a received payload starts with the session's cookie
+func hasCookie(payload, cookie []byte) bool {
+	return bytes.HasPrefix(payload, cookie)
+}
@@ -1,8 +1,13 @@
 package ping
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/url"
 	"os"
 	"sync"
 	"time"
@@ -12,158 +17,484 @@ import (
 	"golang.org/x/net/ipv6"
 )
 
+// Config is the request of a Ping, set from the
+// command-line flags and the host name.
+type Config struct {
+	TTL          TimeToLive    // time to live (uint32)
+	TOS          TypeOfService // if set, tos (ipv4) / traffic class (ipv6)
+	PacketSize   PacketSize    // packet size (uint16)
+	Count        Count         // if set, number of echo response packets sent and received
+	Timeout      Timeout       // if set, time before program exits
+	Deadline     Deadline      // if set, time before program exits, sending until count replies arrive
+	Flood        Flood         // flood mode
+	Adaptive     Adaptive      // adaptive mode
+	Quiet        Quiet         // only output summaries
+	Outstanding  Outstanding   // report replies missing once their wait time passed
+	Numeric      Numeric       // do not look up the names of reply addresses
+	Summary      Summary       // if set, interval between periodic summaries
+	Reresolve    Reresolve     // if set, interval between re-resolving the host name
+	MaxLoss      MaxLoss       // if set, max packet loss in percent for the run to succeed (see Check)
+	MaxRTT       MaxRTT        // if set, max average round-trip time for the run to succeed (see Check)
+	Wait         Wait          // wait time between sending pings
+	Rate         Rate          // if set, rate packets are sent at (packets or bits per second)
+	Burst        Burst         // packets that can be sent back-to-back
+	Preload      Preload       // if set, packets sent back-to-back before the normal cadence
+	WaitTime     WaitTime      // max round-trip time for outputting response
+	Probe        Probe         // probe used to reach the host (icmp, tcp:port, udp:port, http, https)
+	Source       Source        // if set, source address of outgoing packets
+	Interface    Interface     // if set, interface packets are sent from and received on
+	IPv4Only     IPv4Only      // only ping the ipv4 addresses of the host
+	IPv6Only     IPv6Only      // only ping the ipv6 addresses of the host
+	AllAddresses AllAddresses  // ping every address of the host, each with its own stats
+	DualStack    DualStack     // ping the ipv4 and ipv6 addresses of the host, comparing them
+	HostName     string        // host name as a string
+	Resolver     Resolver      // if set, looks up the host and the names of reply addresses in place of the system resolver
+	Output       io.Writer     // if set, writer the output is written to in place of stdout
+	TLSConfig    *tls.Config   // if set, tls config of https probes, such as the trusted root certificates
+}
+
 // Ping is used to represent a request to
 // send ICMP "echo requests" to a particular host.
 type Ping struct {
-	TTL          TimeToLive          // time to live (uint32)
-	PacketSize   PacketSize          // packet size (uint16)
-	Count        Count               // if set, number of echo response packets sent and received
-	Timeout      Timeout             // if set, time before program exits
-	Flood        Flood               // flood mode
-	Wait         Wait                // wait time between sending pings
-	WaitTime     WaitTime            // max round-trip time for outputting response
-	HostName     string              // host name as a string
-	hostAddr     *net.IPAddr         // host as an address
-	isIPv4       bool                // if the host is IPv4
-	proto        int                 // iana protocol
-	conn         *icmp.PacketConn    // connection for sending/receiving
-	id           int                 // id for requests/responses
-	requestType  icmp.Type           // ICMP request type
-	replyType    icmp.Type           // ICMP response type
-	sent         map[int]*icmpPacket // sent sequences (seq -> sent packet)
-	sentMux      sync.Mutex          // mutex for sent map
-	floodRecv    int                 // how many packets received in the last second
-	floodRecvMux sync.Mutex          // mutex for flood recv
-	waitGroup    sync.WaitGroup      // wait group to wait for all helper goroutines to finish
+	Config
+	hostOverride *net.IPAddr        // if set, address pinged in place of resolving the host name
+	children     []*Ping            // pings of each address of the host, if pinging all addresses
+	hostAddr     *net.IPAddr        // host as an address, which may change if re-resolved
+	isIPv4       bool               // if the host is IPv4
+	proto        int                // iana protocol
+	iface        *net.Interface     // interface sockets are bound to, nil if unbound
+	conn         net.PacketConn     // connection for sending/receiving
+	dialOverride contextDialer      // if set, dials tcp and http probes in place of the dialer
+	txTimestamps bool               // if kernel transmit timestamps are enabled on the connection
+	txBuffer     []byte             // buffer transmit timestamps are read into, reused with sentMux held
+	ipv4Conn     *ipv4.PacketConn   // ipv4 view of the icmp connection, nil otherwise
+	ipv6Conn     *ipv6.PacketConn   // ipv6 view of the icmp connection, nil otherwise
+	udpLatestSeq uint64             // latest sequence the udp responder replied to
+	udpResponded uint64             // datagrams the udp responder received up to the latest sequence
+	httpURL      *url.URL           // url requested by http probes
+	isGroupHost  bool               // if the host is a multicast or broadcast address
+	names        *nameCache         // names of reply addresses, looked up in the background
+	id           int                // random id for requests/responses
+	cookie       []byte             // random cookie at the start of each payload
+	requestType  icmp.Type          // ICMP request type
+	sendBuffer   []byte             // buffer echo requests are marshalled into, reused by the sender
+	replyType    icmp.Type          // ICMP response type
+	sent         window             // packets in flight, by sequence
+	latestSeq    uint64             // latest sequence sent
+	stats        rttStats           // statistics of the probes sent
+	period       rttStats           // statistics of the probes resolved since the previous periodic summary
+	periodSeq    uint64             // number of the current periodic summary, starting at 1
+	segments     []addressSegment   // statistics of each address the host was pinged at
+	lossesInRow  uint64             // missing replies in a row, until re-resolving
+	httpPhases   httpPhases         // sum of the phases of received http probes
+	sentMux      sync.Mutex         // mutex for sent packets and stats
+	scheduler    *scheduler         // wait time expiries of sent sequences
+	replies      chan *replyPacket  // replies read but not yet processed
+	recvNotify   chan struct{}      // packets received that the flood or adaptive sender can send another request for
+	resolved     chan struct{}      // notifies that a packet was received or exceeded its wait time
+	lossNotify   chan struct{}      // notifies the re-resolver of missing replies in a row
+	waitGroup    sync.WaitGroup     // wait group to wait for all helper goroutines to finish
+	ctx          context.Context    // context for in-flight probes
+	cancel       context.CancelFunc // cancels in-flight probes
+	stop         chan struct{}      // closed to stop the run early, shared by child pings
+	interrupt    bool               // if interrupts stop the run, as when started by Start
+	writer       io.Writer          // output of the run with serialized writes, shared by child pings
 }
 
 // Validate checks if the Ping request is valid,
 // returning a non-nil error if invalid.
 // Should be called before calling Start().
 // Requirements:
-// 		Count > 0
-//		Host must be valid IPv4 or IPv6 address
-//		Cannot have both wait flag (-i) and flood flag (-f) at a time
+//
+//	Count > 0
+//	0 < Preload <= Count and Preload <= 65536
+//	Host must be valid IPv4 or IPv6 address
+//	Cannot have both wait flag (-i) and flood flag (-f) at a time
+//	Cannot have rate flag (-r) with wait flag (-i) or flood flag (-f)
+//	Cannot have adaptive flag (-A) with flood flag (-f) or rate flag (-r)
+//	Cannot have both ipv4 flag (-4) and ipv6 flag (-6) at a time
+//	Cannot have re-resolve flag (-R) with all addresses flag (-a)
+//	Cannot have dual-stack flag (-D) with -4, -6, -a, -R or source flag (-S)
+//	Host must have an address in the forced address family
+//	Host must have both an IPv4 and an IPv6 address with -D
+//	Source must be from the same address family as the host
+//	Interface must match the zone of a link-local host
+//
+// The error is a *ResolveError if the host cannot be resolved,
+// or a *ConfigError otherwise.
 func (p *Ping) Validate() error {
+	err := p.validate()
+	var resolveErr *ResolveError
+	if err == nil || errors.As(err, &resolveErr) {
+		return err
+	}
+	return &ConfigError{Err: err}
+}
+
+// checks the requirements of Validate
+func (p *Ping) validate() error {
 	if p.Count.IsSet && p.Count.Value == 0 {
 		return errCountInvalid
 	}
-	if _, _, err := ResolveHost(p.HostName); err != nil {
+	if p.Preload.IsSet && (p.Preload.Value == 0 || p.Preload.Value > preloadMax) {
+		return errPreloadInvalid
+	}
+	if p.Preload.IsSet && p.Count.IsSet && p.Preload.Value > p.Count.Value {
+		return errPreloadTooLarge
+	}
+	if bool(p.IPv4Only) && bool(p.IPv6Only) {
+		return fmt.Errorf("incompatible flags: -%v and -%v", ipv4OnlyFlag, ipv6OnlyFlag)
+	}
+	if p.Reresolve.IsSet && bool(p.AllAddresses) {
+		return fmt.Errorf("incompatible flags: -%v and -%v", reresolveFlag, allAddressesFlag)
+	}
+	if err := p.validateDualStack(); err != nil {
+		return err
+	}
+	if bool(p.DualStack) {
+		if _, err := p.resolveDualStack(); err != nil {
+			return err
+		}
+	}
+	addr, IPv4, err := p.resolveHost()
+	if err != nil {
 		return err
 	}
 	if p.Wait.IsSet && bool(p.Flood) {
 		return fmt.Errorf("incompatible flags: -%v and -%v", waitFlag, floodFlag)
 	}
+	if p.Rate.IsSet && p.Wait.IsSet {
+		return fmt.Errorf("incompatible flags: -%v and -%v", rateFlag, waitFlag)
+	}
+	if p.Rate.IsSet && bool(p.Flood) {
+		return fmt.Errorf("incompatible flags: -%v and -%v", rateFlag, floodFlag)
+	}
+	if bool(p.Adaptive) && bool(p.Flood) {
+		return fmt.Errorf("incompatible flags: -%v and -%v", adaptiveFlag, floodFlag)
+	}
+	if bool(p.Adaptive) && p.Rate.IsSet {
+		return fmt.Errorf("incompatible flags: -%v and -%v", adaptiveFlag, rateFlag)
+	}
+	if p.Source.IsSet && (p.Source.Value.To4() != nil) != IPv4 {
+		return errSourceFamilyInvalid
+	}
+	if p.Interface.IsSet && addr.Zone != "" && addr.Zone != p.Interface.Value {
+		return errInterfaceMismatch
+	}
 	return nil
 }
 
 // initializes the Ping's private fields
-// for a packet connection and request/reply ICMP types
+// for the probe used to reach the host
 func (p *Ping) init() error {
+	err := p.initSession()
+	if err != nil {
+		return err
+	}
+	switch p.Probe.Protocol {
+	case probeTCP:
+		return nil // tcp probes dial the host for each handshake
+	case probeHTTP, probeHTTPS:
+		return p.initHTTP()
+	case probeUDP:
+		err = p.initUDP()
+	default:
+		err = p.initICMP()
+	}
+	if err != nil {
+		return err
+	}
+	// take round-trip times from kernel timestamps where supported,
+	// which the system time is used in place of otherwise
+	_, p.txTimestamps = enableTimestamps(p.conn)
+	if p.txTimestamps {
+		p.txBuffer = make([]byte, txTimestampBufferSize)
+	}
+	return nil
+}
+
+// initializes the Ping's private fields for a session
+// with the host, before opening any connection
+func (p *Ping) initSession() error {
 	// resolve host
-	addr, IPv4, err := ResolveHost(p.HostName)
+	addr, IPv4, err := p.resolveHost()
 	if err != nil {
-		panic(err)
+		return err
 	}
 	p.hostAddr = addr
 	p.isIPv4 = IPv4
-	// initialize packet connection, req/resp types
-	var icmpNetwork, bindAddress string
-	if p.isIPv4 {
-		icmpNetwork = ipv4ICMPNetwork
-		bindAddress = ipv4BindAddress
-		p.requestType = ipv4.ICMPTypeEcho
-		p.replyType = ipv4.ICMPTypeEchoReply
-		p.proto = ianaProtocolIPv4ICMP
-	} else {
-		icmpNetwork = ipv6ICMPNetwork
-		bindAddress = ipv6BindAddress
-		p.requestType = ipv6.ICMPTypeEchoRequest
-		p.replyType = ipv6.ICMPTypeEchoReply
-		p.proto = ianaProtocolIPv6ICMP
+	// get interface to bind to, if any
+	p.iface, err = p.boundInterface()
+	if err != nil {
+		return fmt.Errorf("failed to get interface: %w", err)
 	}
-	conn, err := icmp.ListenPacket(icmpNetwork, bindAddress)
+	p.isGroupHost = isGroupAddress(addr.IP)
+	// set random id and cookie for the session, rather than the
+	// process id, which easily collides with other pingers
+	p.id, p.cookie, err = p.PacketSize.generateSession()
+	if err != nil {
+		return fmt.Errorf("failed to generate session: %w", err)
+	}
+	// initialize window, stats and mutexes
+	p.sent = newWindow()
+	p.stats = rttStats{}
+	p.period = rttStats{}
+	p.periodSeq = 1
+	p.segments = []addressSegment{{addr: addr, start: time.Now()}}
+	p.lossesInRow = 0
+	p.httpPhases = httpPhases{}
+	p.names = newNameCache(p.resolver())
+	p.sentMux = sync.Mutex{}
+	p.recvNotify = make(chan struct{}, floodTimesPerSecond)
+	p.resolved = make(chan struct{}, 1)
+	p.lossNotify = make(chan struct{}, 1)
+	// create scheduler and reply queue for the processing loop
+	p.scheduler = newScheduler()
+	p.replies = make(chan *replyPacket, replyQueueSize)
+	// create wait group
+	p.waitGroup = sync.WaitGroup{}
+	// create context for in-flight probes
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return nil
+}
+
+// initializes the Ping's private fields
+// for a packet connection and request/reply ICMP types
+func (p *Ping) initICMP() error {
+	// initialize packet connection, req/resp types
+	icmpNetwork, bindAddress := p.initICMPTypes()
+	conn, err := p.listen(icmpNetwork, bindAddress)
 	if err != nil {
-		return fmt.Errorf("failed to get packet conn: %v", err)
+		return &SocketError{Op: "get packet conn", Err: err}
 	}
-	// set ttl (ipv4) / hop limit (ipv6)
+	// set ttl (ipv4) / hop limit (ipv6) and tos (ipv4) / traffic class (ipv6),
+	// and receive the interface of each reply, where the ipv4 header holds
+	// the ttl and tos of a reply, and ipv6 control messages hold them
 	if p.isIPv4 {
-		conn.IPv4PacketConn().SetTTL(int(p.TTL))
+		p.ipv4Conn = ipv4.NewPacketConn(conn)
+		p.ipv4Conn.SetTTL(int(p.TTL))
+		if p.TOS.IsSet {
+			err = p.ipv4Conn.SetTOS(int(p.TOS.Value))
+		}
+		if err == nil {
+			err = p.ipv4Conn.SetControlMessage(ipv4ControlFlags, true)
+		}
 	} else {
-		conn.IPv6PacketConn().SetHopLimit(int(p.TTL))
+		p.ipv6Conn = ipv6.NewPacketConn(conn)
+		p.ipv6Conn.SetHopLimit(int(p.TTL))
+		if p.TOS.IsSet {
+			err = p.ipv6Conn.SetTrafficClass(int(p.TOS.Value))
+		}
+		if err == nil {
+			err = p.ipv6Conn.SetControlMessage(ipv6ControlFlags, true)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return &SocketError{Op: "set socket options", Err: err}
 	}
 	// set packet connection
 	p.conn = conn
-	// set id based on process id
-	p.id = os.Getpid()
-	// initialize maps and mutexes
-	p.sent = make(map[int]*icmpPacket)
-	p.sentMux = sync.Mutex{}
-	p.floodRecvMux = sync.Mutex{}
-	// create wait group
-	p.waitGroup = sync.WaitGroup{}
 	return nil
 }
 
+// initializes the request/reply ICMP types and protocol for
+// the family of the host, returning the network and bind
+// address of its packet connection
+func (p *Ping) initICMPTypes() (icmpNetwork, bindAddress string) {
+	if p.isIPv4 {
+		p.requestType = ipv4.ICMPTypeEcho
+		p.replyType = ipv4.ICMPTypeEchoReply
+		p.proto = ianaProtocolIPv4ICMP
+		return ipv4ICMPNetwork, ipv4BindAddress
+	}
+	p.requestType = ipv6.ICMPTypeEchoRequest
+	p.replyType = ipv6.ICMPTypeEchoReply
+	p.proto = ianaProtocolIPv6ICMP
+	return ipv6ICMPNetwork, ipv6BindAddress
+}
+
 // Start begins the ICMP "echo requests"
-// using the Ping request.
-// Will panic if the Ping request has invalid arguments
-// determined by Validate().
+// using the Ping request, stopping early on an interrupt.
+// A Ping can only be started once, see Pinger to run
+// the same request repeatedly.
+// Returns the error of Validate() if the Ping request is
+// invalid, a *ResolveError if the host cannot be resolved,
+// a *SocketError if its socket cannot be opened, or a
+// *SendError if a probe cannot be sent.
 func (p *Ping) Start() error {
 	err := p.Validate()
 	if err != nil {
-		panic("invalid ping: " + err.Error())
+		return err
+	}
+	p.stop = make(chan struct{})
+	p.interrupt = true
+	return p.start()
+}
+
+// pings the host until the run ends, printing the stats, once the
+// Ping is validated and its stop channel made
+func (p *Ping) start() error {
+	p.writer = &syncWriter{w: p.output()}
+	switch {
+	case bool(p.AllAddresses):
+		return p.startAll()
+	case bool(p.DualStack):
+		return p.startDualStack()
 	}
-	err = p.init()
+	err := p.init()
 	if err != nil {
-		return fmt.Errorf("failed to initialize ping: %v", err)
+		return fmt.Errorf("failed to initialize ping: %w", err)
 	}
 	// print stats if program interrupted
-	createInterruptHandler(p)
-	fmt.Printf("PING %v (%v): %v data bytes\n", p.HostName, p.hostAddr.String(), p.PacketSize)
-	// make channel for timeout
-	timeout := make(chan bool)
-	defer close(timeout)
+	stopInterrupts := p.handleInterrupts()
+	defer stopInterrupts()
+	p.printHeader()
+	err = p.run()
+	// print stats
+	p.printStats()
+	return err
+}
+
+// prints the line describing the host and probe
+// at the start of the run
+func (p *Ping) printHeader() {
+	switch p.Probe.Protocol {
+	case probeTCP:
+		fmt.Fprintf(p.output(), "PING %v (%v)%v: tcp port %v\n", p.HostName, p.hostAddr.String(), p.describeSource(), p.Probe.Port)
+	case probeUDP:
+		fmt.Fprintf(p.output(), "PING %v (%v)%v: udp port %v, %v data bytes\n", p.HostName, p.hostAddr.String(), p.describeSource(), p.Probe.Port, p.PacketSize)
+	case probeHTTP, probeHTTPS:
+		fmt.Fprintf(p.output(), "PING %v (%v)%v: %v %v\n", p.HostName, p.hostAddr.String(), p.describeSource(), httpMethod, p.httpURL)
+	default:
+		fmt.Fprintf(p.output(), "PING %v (%v)%v: %v data bytes\n", p.HostName, p.hostAddr.String(), p.describeSource(), p.PacketSize)
+	}
+}
+
+// sends the probes and handles their replies until the
+// run ends (see wait), once the Ping is initialized
+func (p *Ping) run() error {
+	// make channels for the timeout and deadline, which
+	// are nil and never fire if unset
+	var timeout, deadline <-chan time.Time
 	if p.Timeout.IsSet {
-		go func() {
-			<-time.After(p.Timeout.Value) // wait
-			timeout <- true               // notify timeout
-		}()
+		timeout = time.After(p.Timeout.Value)
+	}
+	if p.Deadline.IsSet {
+		deadline = time.After(p.Deadline.Value)
 	}
 	// make channels for done, errors
 	// done will notify threads to clean up and stop
-	// errors will be used to pass errors from threads
+	// errors will be used to pass errors from threads,
+	// buffered for the sender and receiver
 	done := make(chan bool)
-	errors := make(chan error)
-	defer close(errors)
-	defer close(done)
-	p.waitGroup.Add(1)
-	// start receiving
-	go p.receiver(done, errors)
+	errors := make(chan error, 2)
+	if p.Probe.Protocol == probeICMP || p.Probe.Protocol == probeUDP {
+		// start receiving and processing, other probes handle their own replies
+		p.waitGroup.Add(2)
+		go p.receiver(done, errors)
+		go p.processor(done)
+	}
+	if p.Summary.IsSet {
+		// start periodic summaries
+		p.waitGroup.Add(1)
+		go p.summarizer(done)
+	}
+	if p.Reresolve.IsSet {
+		// start re-resolving the host name
+		p.waitGroup.Add(1)
+		go p.reresolver(done)
+	}
 	p.waitGroup.Add(1)
 	// start sending
-	if bool(p.Flood) {
+	switch {
+	case bool(p.Flood):
 		go p.floodSender(done, errors)
-	} else {
+	case bool(p.Adaptive):
+		go p.adaptiveSender(done, errors)
+	default:
 		go p.sender(done, errors)
 	}
-	// clean up function that notifies sender/receiver to stop
-	cleanUp := func() {
-		done <- true
-		done <- true
-	}
-	err = nil
-	// wait for an error to occur or a timeout
-	// note: an error can be nil, indicating a successful
-	// sender termination if we are sending finite packets
-	select {
-	case <-timeout:
-	case err = <-errors:
+	err := p.wait(timeout, deadline, errors)
+	// notify sender/receiver to stop and cancel in-flight probes
+	close(done)
+	p.cancel()
+	if p.conn != nil {
+		p.conn.SetReadDeadline(time.Now()) // unblock the receiver
 	}
-	go cleanUp()
 	// wait for all threads to clean up
 	p.waitGroup.Wait()
-	// print stats
-	p.printStats()
+	if p.conn != nil {
+		p.conn.Close()
+	}
 	return err
 }
+
+// releases the connection and context of a
+// Ping that was initialized but never run
+func (p *Ping) close() {
+	p.cancel()
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// gets the writer the output is written to
+func (p *Ping) output() io.Writer {
+	switch {
+	case p.writer != nil:
+		return p.writer
+	case p.Output == nil:
+		return os.Stdout
+	default:
+		return p.Output
+	}
+}
+
+// represents a writer whose writes are serialized, since the
+// probes, summaries and interim stats of a run print at once
+type syncWriter struct {
+	w   io.Writer
+	mux sync.Mutex
+}
+
+func (w *syncWriter) Write(b []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.w.Write(b)
+}
+
+// waits for the run to end, which is on a timeout, the deadline,
+// an interrupt, an error, once count replies arrived if the
+// deadline is set, or once the sender finished and the packets
+// still in flight were received or exceeded their wait time,
+// lingering for them up to the wait time
+// note: an error can be nil, indicating a successful
+// sender termination if we are sending finite packets
+func (p *Ping) wait(timeout, deadline <-chan time.Time, errors <-chan error) error {
+	var linger <-chan time.Time // set once the sender finished
+	for {
+		select {
+		case <-timeout:
+			return nil
+		case <-deadline:
+			return nil
+		case <-p.stop:
+			return nil // interrupted
+		case <-linger:
+			return nil // stop waiting for the packets in flight
+		case err := <-errors:
+			if err != nil {
+				return err
+			}
+			linger = time.After(time.Duration(p.WaitTime))
+		case <-p.resolved: // a packet was received or exceeded its wait time
+		}
+		if p.Deadline.IsSet && p.countReceived() {
+			return nil // stop early, as count replies arrived
+		}
+		if linger != nil && p.outstanding() == 0 {
+			return nil // no packets in flight
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package ping
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// Option sets a field of the Config of a Pinger,
+// returning a non-nil error if its value is invalid,
+// which is the error of the matching command-line flag.
+type Option func(*Config) error
+
+// Pinger is used to represent a validated request that can be
+// run repeatedly and concurrently, where each run has its own
+// connection and statistics.
+// Create one with New.
+type Pinger struct {
+	config Config // request of each run
+}
+
+// Statistics are the statistics of a run for an address of the host.
+type Statistics struct {
+	Addr        *net.IPAddr   // address pinged, the latest one if re-resolved
+	Transmitted uint64        // packets sent
+	Received    uint64        // packets received, including late ones
+	Exceeded    uint64        // packets received after their wait time
+	Loss        float64       // percentage of packets lost
+	Min         time.Duration // min round-trip time
+	Avg         time.Duration // average round-trip time
+	Max         time.Duration // max round-trip time
+	StdDev      time.Duration // standard deviation of the round-trip times
+}
+
+// DefaultConfig gets the Config of a request to ping a host with
+// the defaults of the command-line flags: ICMP echo requests of 56
+// data bytes sent every second with the system's default ttl, until
+// stopped, with a wait time of 4 seconds.
+func DefaultConfig(host string) Config {
+	c := Config{HostName: host}
+	// only these flags have defaults, the others are unset when zeroed
+	c.TTL.Init()
+	c.PacketSize.Init()
+	c.Wait.Init()
+	c.WaitTime.Init()
+	c.Burst.Init()
+	c.Probe.Init()
+	return c
+}
+
+// New creates a Pinger for a host from the DefaultConfig, with
+// the options applied in order, and validates it (see Validate).
+// The error is a *ConfigError if an option or the request is
+// invalid, or a *ResolveError if the host cannot be resolved.
+func New(host string, opts ...Option) (*Pinger, error) {
+	c := DefaultConfig(host)
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, &ConfigError{Err: err}
+		}
+	}
+	err := (&Ping{Config: c}).Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &Pinger{config: c}, nil
+}
+
+// Config gets a copy of the request of the Pinger.
+func (p *Pinger) Config() Config {
+	return p.config
+}
+
+// Run pings the host with a new Ping until the run ends or the
+// context is done, printing its output like Start but leaving the
+// signals of the program alone. Concurrent runs share the Output
+// of the Pinger, which must then be safe for concurrent writes.
+// Returns the statistics of each address pinged, along with the
+// error of Start if the run failed, the error of the context if
+// it stopped the run early, or the error of Check otherwise.
+func (p *Pinger) Run(ctx context.Context) ([]Statistics, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	run := &Ping{Config: p.config, stop: make(chan struct{})}
+	// stop the run once the context is done, unless it already
+	// finished, so a run is only reported as stopped by the
+	// context if it was stopped before it returned
+	var mux sync.Mutex
+	finished, stopped := false, false
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			mux.Lock()
+			if !finished {
+				stopped = true
+				close(run.stop)
+			}
+			mux.Unlock()
+		case <-done:
+		}
+	}()
+	err := run.start()
+	mux.Lock()
+	finished = true
+	mux.Unlock()
+	switch {
+	case err != nil:
+		return run.Statistics(), err
+	case stopped:
+		return run.Statistics(), ctx.Err()
+	default:
+		return run.Statistics(), run.Check()
+	}
+}
+
+// Statistics gets the statistics of the run so far, of each
+// address if pinging all of them, or nil if it never started.
+func (p *Ping) Statistics() []Statistics {
+	if p.children != nil {
+		var stats []Statistics
+		for _, child := range p.children {
+			stats = append(stats, child.Statistics()...)
+		}
+		return stats
+	}
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	if p.hostAddr == nil {
+		return nil // never initialized
+	}
+	return []Statistics{{
+		Addr:        p.hostAddr,
+		Transmitted: p.stats.transmitted,
+		Received:    p.stats.received,
+		Exceeded:    p.stats.exceeded,
+		Loss:        p.stats.loss(),
+		Min:         p.stats.min,
+		Avg:         p.stats.avg(),
+		Max:         p.stats.max,
+		StdDev:      p.stats.stdDev(),
+	}}
+}
+
+// WithCount sets the number of packets to send (see Count).
+func WithCount(count uint32) Option {
+	return func(c *Config) error {
+		c.Count = Count{IsSet: true, Value: count}
+		return nil
+	}
+}
+
+// WithInterval sets the wait time between sending packets (see Wait).
+func WithInterval(interval time.Duration) Option {
+	return func(c *Config) error {
+		if interval < 0 {
+			return errWaitInvalid
+		}
+		c.Wait = Wait{IsSet: true, Value: interval}
+		return nil
+	}
+}
+
+// WithWaitTime sets the max round-trip time of a reply (see WaitTime).
+func WithWaitTime(waitTime time.Duration) Option {
+	return func(c *Config) error {
+		if waitTime < 0 {
+			return errWaitTimeInvalid
+		}
+		c.WaitTime = WaitTime(waitTime)
+		return nil
+	}
+}
+
+// WithTimeout sets the time before the run ends (see Timeout).
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Config) error {
+		if timeout < 0 {
+			return errTimeoutInvalid
+		}
+		c.Timeout = Timeout{IsSet: true, Value: timeout}
+		return nil
+	}
+}
+
+// WithDeadline sets the time before the run ends, sending
+// until count replies arrive (see Deadline).
+func WithDeadline(deadline time.Duration) Option {
+	return func(c *Config) error {
+		if deadline <= 0 {
+			return errDeadlineInvalid
+		}
+		c.Deadline = Deadline{IsSet: true, Value: deadline}
+		return nil
+	}
+}
+
+// WithTTL sets the time to live of outgoing packets (see TimeToLive).
+func WithTTL(ttl uint32) Option {
+	return func(c *Config) error {
+		c.TTL = TimeToLive(ttl)
+		return nil
+	}
+}
+
+// WithPacketSize sets the data bytes of each packet (see PacketSize).
+func WithPacketSize(size uint16) Option {
+	return func(c *Config) error {
+		if size > packetPayloadSizeMax {
+			return fmt.Errorf("%v: %v > %v", packetSizeTooLarge, size, packetPayloadSizeMax)
+		}
+		c.PacketSize = PacketSize(size)
+		return nil
+	}
+}
+
+// WithProbe sets the probe used to reach the host, in the
+// format of the command-line flag, such as "tcp:443" (see Probe).
+func WithProbe(probe string) Option {
+	return func(c *Config) error {
+		return c.Probe.Set(probe)
+	}
+}
+
+// WithRate sets the rate packets are sent at, in the format
+// of the command-line flag, such as "500" or "10mbps" (see Rate).
+func WithRate(rate string) Option {
+	return func(c *Config) error {
+		return c.Rate.Set(rate)
+	}
+}
+
+// WithFlood sends packets as fast as they are
+// received, at least 100 per second (see Flood).
+func WithFlood() Option {
+	return func(c *Config) error {
+		c.Flood = true
+		return nil
+	}
+}
+
+// WithAdaptive sends packets at the pace of
+// the replies to the previous ones (see Adaptive).
+func WithAdaptive() Option {
+	return func(c *Config) error {
+		c.Adaptive = true
+		return nil
+	}
+}
+
+// WithBurst sets the number of packets sent back-to-back
+// when catching up with the rate (see Burst).
+func WithBurst(burst uint32) Option {
+	return func(c *Config) error {
+		if burst == 0 {
+			return errBurstInvalid
+		}
+		c.Burst = Burst(burst)
+		return nil
+	}
+}
+
+// WithPreload sets the number of packets sent back-to-back
+// before the normal cadence (see Preload).
+func WithPreload(preload uint32) Option {
+	return func(c *Config) error {
+		if preload == 0 || preload > preloadMax {
+			return errPreloadInvalid
+		}
+		c.Preload = Preload{IsSet: true, Value: preload}
+		return nil
+	}
+}
+
+// WithTOS sets the type of service (ipv4) or traffic
+// class (ipv6) of outgoing packets (see TypeOfService).
+func WithTOS(tos uint8) Option {
+	return func(c *Config) error {
+		c.TOS = TypeOfService{IsSet: true, Value: tos}
+		return nil
+	}
+}
+
+// WithSource sets the source address of outgoing packets (see Source).
+func WithSource(addr string) Option {
+	return func(c *Config) error {
+		return c.Source.Set(addr)
+	}
+}
+
+// WithInterface sets the interface packets are sent
+// from and received on (see Interface).
+func WithInterface(name string) Option {
+	return func(c *Config) error {
+		return c.Interface.Set(name)
+	}
+}
+
+// WithIPv4Only only pings the ipv4 addresses of the host.
+func WithIPv4Only() Option {
+	return func(c *Config) error {
+		c.IPv4Only = true
+		return nil
+	}
+}
+
+// WithIPv6Only only pings the ipv6 addresses of the host.
+func WithIPv6Only() Option {
+	return func(c *Config) error {
+		c.IPv6Only = true
+		return nil
+	}
+}
+
+// WithAllAddresses pings every address of the host,
+// each with its own statistics (see AllAddresses).
+func WithAllAddresses() Option {
+	return func(c *Config) error {
+		c.AllAddresses = true
+		return nil
+	}
+}
+
+// WithDualStack pings an ipv6 and an ipv4 address of
+// the host, each with its own statistics (see DualStack).
+func WithDualStack() Option {
+	return func(c *Config) error {
+		c.DualStack = true
+		return nil
+	}
+}
+
+// WithReresolve sets the interval between resolving the
+// host again, following its dns changes (see Reresolve).
+func WithReresolve(interval time.Duration) Option {
+	return func(c *Config) error {
+		if interval <= 0 {
+			return errReresolveInvalid
+		}
+		c.Reresolve = Reresolve{IsSet: true, Value: interval}
+		return nil
+	}
+}
+
+// WithMaxLoss sets the max packet loss in percent
+// for a run to succeed (see Check).
+func WithMaxLoss(percent float64) Option {
+	return func(c *Config) error {
+		if math.IsNaN(percent) || percent < 0 || percent > 100 {
+			return errMaxLossInvalid
+		}
+		c.MaxLoss = MaxLoss{IsSet: true, Value: percent}
+		return nil
+	}
+}
+
+// WithMaxRTT sets the max average round-trip time
+// for a run to succeed (see Check).
+func WithMaxRTT(rtt time.Duration) Option {
+	return func(c *Config) error {
+		if rtt <= 0 {
+			return errMaxRTTInvalid
+		}
+		c.MaxRTT = MaxRTT{IsSet: true, Value: rtt}
+		return nil
+	}
+}
+
+// WithQuiet only outputs the summary lines (see Quiet).
+func WithQuiet() Option {
+	return func(c *Config) error {
+		c.Quiet = true
+		return nil
+	}
+}
+
+// WithSummary sets the interval between periodic
+// summaries of the packets resolved (see Summary).
+func WithSummary(interval time.Duration) Option {
+	return func(c *Config) error {
+		if interval <= 0 {
+			return errSummaryInvalid
+		}
+		c.Summary = Summary{IsSet: true, Value: interval}
+		return nil
+	}
+}
+
+// WithOutstanding outputs a line for each packet that
+// exceeds its wait time without a reply (see Outstanding).
+func WithOutstanding() Option {
+	return func(c *Config) error {
+		c.Outstanding = true
+		return nil
+	}
+}
+
+// WithNumeric disables looking up the names
+// of reply addresses (see Numeric).
+func WithNumeric() Option {
+	return func(c *Config) error {
+		c.Numeric = true
+		return nil
+	}
+}
+
+// WithResolver sets the resolver looking up the
+// names of reply addresses (see Resolver).
+func WithResolver(resolver Resolver) Option {
+	return func(c *Config) error {
+		c.Resolver = resolver
+		return nil
+	}
+}
+
+// WithTLSConfig sets the tls config of HTTPS probes, such
+// as the trusted root certificates (see TLSConfig).
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *Config) error {
+		c.TLSConfig = config
+		return nil
+	}
+}
+
+// WithOutput sets the writer the output is written to,
+// such as io.Discard to silence it.
+func WithOutput(w io.Writer) Option {
+	return func(c *Config) error {
+		c.Output = w
+		return nil
+	}
+}
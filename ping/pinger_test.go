@@ -0,0 +1,98 @@
+package ping
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOptionsRejectInvalidValues(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		err  error
+	}{
+		{"wait time", WithWaitTime(-time.Second), errWaitTimeInvalid},
+		{"timeout", WithTimeout(-time.Second), errTimeoutInvalid},
+		{"deadline", WithDeadline(-time.Second), errDeadlineInvalid},
+		{"max loss", WithMaxLoss(math.NaN()), errMaxLossInvalid},
+		{"max rtt", WithMaxRTT(0), errMaxRTTInvalid},
+		{"burst", WithBurst(0), errBurstInvalid},
+		{"preload", WithPreload(preloadMax + 1), errPreloadInvalid},
+		{"summary", WithSummary(0), errSummaryInvalid},
+		{"reresolve", WithReresolve(-time.Second), errReresolveInvalid},
+	}
+	for _, test := range tests {
+		_, err := New("127.0.0.1", test.opt)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: expected %v, got %v", test.name, test.err, err)
+		}
+	}
+	var configErr *ConfigError
+	if _, err := New("127.0.0.1", WithPacketSize(packetPayloadSizeMax+1)); !errors.As(err, &configErr) {
+		t.Errorf("packet size: expected a *ConfigError, got %v", err)
+	}
+}
+
+func TestRunReturnsContextError(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	// the handshakes are answered by the kernel, so nothing needs to accept them
+	p, err := New("127.0.0.1", WithProbe("tcp:"+listenerPort(t, listener)),
+		WithNumeric(), WithOutput(io.Discard))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	stats, err := p.Run(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if len(stats) != 1 || stats[0].Transmitted == 0 {
+		t.Errorf("expected the statistics of the run, got %+v", stats)
+	}
+}
+
+func TestRunConcurrently(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	const runs, count = 4, 3
+	p, err := New("127.0.0.1", WithProbe("tcp:"+listenerPort(t, listener)), WithCount(count),
+		WithInterval(10*time.Millisecond), WithNumeric(), WithOutput(io.Discard))
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+	// each run has its own statistics, however many run at once
+	checkRun := func(stats []Statistics, err error) {
+		if err != nil {
+			t.Errorf("Run() failed: %v", err)
+		}
+		if len(stats) != 1 || stats[0].Transmitted != count || stats[0].Received != count {
+			t.Errorf("expected %v/%v packets received, got %+v", count, count, stats)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkRun(p.Run(context.Background()))
+		}()
+	}
+	wg.Wait()
+	// and a run after others starts from scratch
+	checkRun(p.Run(context.Background()))
+	checkRun(p.Run(context.Background()))
+}
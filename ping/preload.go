@@ -0,0 +1,88 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// Preload constants based off the man page for iputils 'ping'.
+	preloadFlag = "l"
+	preloadHelp = "Set the number of packets sent back-to-back before switching\n" +
+		"to the normal wait (-i), rate (-r), adaptive (-A) or flood (-f)\n" +
+		"cadence, to probe queues and policer burst sizes. If unset, no\n" +
+		"packets are preloaded. The preload cannot exceed count (-c), nor\n" +
+		"the 65536 packets tracked at a time."
+	preloadInvalid  = "preload must be greater than 0 and at most 65536"
+	preloadTooLarge = "preload must not exceed count"
+	preloadMax      = windowSize // more could not be told apart
+)
+
+var (
+	// errors for invalid preload
+	errPreloadInvalid  = errors.New(preloadInvalid)
+	errPreloadTooLarge = errors.New(preloadTooLarge)
+)
+
+// Preload is a wrapper around a boolean and unsigned integer
+// to use for command-line argument flag parsing.
+type Preload struct {
+	IsSet bool
+	Value uint32
+}
+
+// Init initializes a Preload instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Preload) Init() {
+}
+
+// String is used to format Preload's value and is required
+// to satisfy the flag.Value interface.
+func (l *Preload) String() string {
+	return fmt.Sprintf("set=%v, value=%v", l.IsSet, l.Value)
+}
+
+// Set will initialize Preload's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (l *Preload) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res <= 0 || res > preloadMax {
+		return errPreloadInvalid
+	}
+	l.IsSet = true
+	l.Value = uint32(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Preload.
+func (*Preload) Flag() string {
+	return preloadFlag
+}
+
+// Help gets the command-line help for Preload.
+func (*Preload) Help() string {
+	return preloadHelp
+}
+
+// sends the preloaded "echo requests" back-to-back,
+// returning the number sent, which is the next sequence,
+// and false if done was signalled before all were sent
+func (p *Ping) sendPreload(done <-chan bool) (uint64, bool, error) {
+	var i uint64
+	for ; p.Preload.IsSet && i < uint64(p.Preload.Value); i++ {
+		select {
+		case <-done:
+			return i, false, nil // stop sending
+		default:
+		}
+		if err := p.send(i); err != nil {
+			return i, true, err
+		}
+	}
+	return i, true, nil
+}
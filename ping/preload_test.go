@@ -0,0 +1,38 @@
+package ping
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPreloadThenInterval(t *testing.T) {
+	const interval = 80 * time.Millisecond
+	times, _ := runEcho(t, 0, WithCount(5), WithPreload(3), WithInterval(interval))
+	if len(times) != 5 {
+		t.Fatalf("expected 5 packets sent, got %v", len(times))
+	}
+	gaps := gaps(times)
+	for i, got := range gaps[:2] {
+		if got > gapSlack {
+			t.Errorf("expected preloaded send %v back-to-back, got %v after the previous one", i+1, got)
+		}
+	}
+	// the preload stands for the first send, so the cadence starts from it
+	if got := times[3].Sub(times[0]); got < interval-time.Millisecond || got > interval+gapSlack {
+		t.Errorf("expected the first send after the preload about %v after the first one, got %v", interval, got)
+	}
+	if got := gaps[3]; got < interval-time.Millisecond || got > interval+gapSlack {
+		t.Errorf("expected the last send about %v after the previous one, got %v", interval, got)
+	}
+}
+
+func TestPreloadStopped(t *testing.T) {
+	p := newTestPing(t, true)
+	p.Preload = Preload{IsSet: true, Value: 3}
+	done := make(chan bool)
+	close(done)
+	sent, ok, err := p.sendPreload(done)
+	if sent != 0 || ok || err != nil {
+		t.Errorf("expected nothing sent once done, got %v sent, ok %v, err %v", sent, ok, err)
+	}
+}
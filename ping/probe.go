@@ -0,0 +1,110 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Probe constants, where ICMP is the default probe of 'ping'.
+	probeFlag = "P"
+	probeHelp = "Set the probe used to reach the host as protocol[:port].\n" +
+		"Supported probes are icmp, tcp:port, udp:port, http[:port] and\n" +
+		"https[:port]. The tcp probe measures the round-trip time of the\n" +
+		"handshake with the port, reporting it as open, closed or filtered.\n" +
+		"The udp probe sends datagrams to a responder (see 'ping responder')\n" +
+		"that reflects them back. The http and https probes request the\n" +
+		"host's root path, reporting the time of each phase of the request.\n" +
+		"If unset, the probe is icmp."
+	probeInvalid     = "probe must be icmp, tcp:port, udp:port, http[:port] or https[:port]"
+	probeSeparator   = ":"
+	probePortBitSize = 16 // ports are unsigned 16-bit integers
+	probeICMP        = "icmp"
+	probeTCP         = "tcp"
+	probeUDP         = "udp"
+	probeHTTP        = "http"
+	probeHTTPS       = "https"
+	probeHTTPPort    = 80  // default port for http
+	probeHTTPSPort   = 443 // default port for https
+)
+
+var (
+	// error for invalid probe
+	errProbeInvalid = errors.New(probeInvalid)
+)
+
+// Probe is a wrapper around a protocol and a port
+// to use for command-line argument flag parsing.
+type Probe struct {
+	Protocol string
+	Port     uint16
+}
+
+// Init initializes a Probe instance by setting its
+// protocol to ICMP.
+func (p *Probe) Init() {
+	p.Protocol = probeICMP
+}
+
+// String is used to format Probe's value and is required
+// to satisfy the flag.Value interface.
+func (p *Probe) String() string {
+	return fmt.Sprintf("protocol=%v, port=%v", p.Protocol, p.Port)
+}
+
+// Set will initialize Probe's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (p *Probe) Set(val string) error {
+	parts := strings.SplitN(val, probeSeparator, 2)
+	protocol, hasPort := parts[0], len(parts) == 2
+	switch protocol {
+	case probeICMP:
+		if hasPort {
+			return errProbeInvalid // icmp has no ports
+		}
+		p.Protocol = protocol
+		p.Port = 0
+		return nil
+	case probeTCP, probeUDP:
+		if !hasPort {
+			return errProbeInvalid // tcp and udp need a port
+		}
+		return p.setPort(protocol, parts[1])
+	case probeHTTP, probeHTTPS:
+		if hasPort {
+			return p.setPort(protocol, parts[1])
+		}
+		p.Protocol = protocol
+		p.Port = probeHTTPPort
+		if protocol == probeHTTPS {
+			p.Port = probeHTTPSPort
+		}
+		return nil
+	default:
+		return errProbeInvalid
+	}
+}
+
+// sets the Probe's protocol and port, returning
+// an error if the port is invalid
+func (p *Probe) setPort(protocol, port string) error {
+	res, err := strconv.ParseUint(port, 10, probePortBitSize)
+	if err != nil || res == 0 {
+		return errProbeInvalid
+	}
+	p.Protocol = protocol
+	p.Port = uint16(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Probe.
+func (*Probe) Flag() string {
+	return probeFlag
+}
+
+// Help gets the command-line help for Probe.
+func (*Probe) Help() string {
+	return probeHelp
+}
@@ -0,0 +1,66 @@
+package ping
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	// Quiet constants based off the man page for 'ping'.
+	quietFlag = "q"
+	quietHelp = "Set the output to quiet. Nothing is output except the\n" +
+		"summary lines at startup time and when finished, along with\n" +
+		"periodic (-e) and interim summaries. If unset, a line is\n" +
+		"output for each packet received."
+)
+
+// Quiet is a wrapper around a boolean
+// to use for command-line argument flag parsing.
+type Quiet bool
+
+// Init initializes a Quiet instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Quiet) Init() {
+}
+
+// String is used to format Quiet's value and is required
+// to satisfy the flag.Value interface.
+func (q *Quiet) String() string {
+	return fmt.Sprintf("value=%v", *q)
+}
+
+// Set will initialize Quiet's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (q *Quiet) Set(val string) error {
+	res, err := strconv.ParseBool(val)
+	if err != nil {
+		return err
+	}
+	*q = Quiet(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Quiet.
+func (*Quiet) Flag() string {
+	return quietFlag
+}
+
+// Help gets the command-line help for Quiet.
+func (*Quiet) Help() string {
+	return quietHelp
+}
+
+// IsBoolFlag is used to notify that Quiet is
+// a boolean flag, so '-q' defaults to '-q=true' or '-q true'.
+func (*Quiet) IsBoolFlag() bool {
+	return true
+}
+
+// prints a line about a single packet, unless quiet
+func (p *Ping) printReply(format string, a ...interface{}) {
+	if bool(p.Quiet) {
+		return
+	}
+	fmt.Fprintf(p.output(), format, a...)
+}
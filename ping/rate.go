@@ -0,0 +1,108 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Rate constants for the rate-controlled sender.
+	rateFlag = "r"
+	rateHelp = "Set the rate packets are sent at, in packets per second, or in\n" +
+		"bits per second of ICMP data (payload and 8 byte header) with a\n" +
+		"bps, kbps or mbps suffix (ex. 10mbps). Packets are sent on an\n" +
+		"absolute timebase, so the rate does not drift. If unset, packets\n" +
+		"are sent at the wait (-i) interval. This flag (-r) is incompatible\n" +
+		"with wait (-i), flood (-f) and adaptive (-A)."
+	rateInvalid      = "rate must be a number of at least 0.001 packets or 1 bit per second"
+	rateMinPackets   = 1e-3 // min packets per second, so the interval fits a time.Duration
+	rateMinBits      = 1    // min bits per second, which is at most a packet every 6 days
+	rateHeaderSize   = 8    // icmp header bytes counted in bits per second
+	rateBitsPerByte  = 8
+	rateSuffixBits   = "bps"
+	rateSuffixKilo   = "kbps"
+	rateSuffixMega   = "mbps"
+	rateBitsKilo     = 1e3
+	rateBitsMega     = 1e6
+	rateFloatBitSize = 64
+)
+
+var (
+	// error for invalid rate
+	errRateInvalid = errors.New(rateInvalid)
+)
+
+// Rate is a wrapper around a boolean and a float, which
+// is in packets per second, or bits per second if IsBits,
+// to use for command-line argument flag parsing.
+type Rate struct {
+	IsSet  bool
+	Value  float64
+	IsBits bool
+}
+
+// Init initializes a Rate instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Rate) Init() {
+}
+
+// String is used to format Rate's value and is required
+// to satisfy the flag.Value interface.
+func (r *Rate) String() string {
+	return fmt.Sprintf("set=%v, value=%v, bits=%v", r.IsSet, r.Value, r.IsBits)
+}
+
+// Set will initialize Rate's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (r *Rate) Set(val string) error {
+	val = strings.ToLower(val)
+	multiplier, isBits := 1.0, true
+	switch {
+	case strings.HasSuffix(val, rateSuffixKilo):
+		val, multiplier = strings.TrimSuffix(val, rateSuffixKilo), rateBitsKilo
+	case strings.HasSuffix(val, rateSuffixMega):
+		val, multiplier = strings.TrimSuffix(val, rateSuffixMega), rateBitsMega
+	case strings.HasSuffix(val, rateSuffixBits):
+		val = strings.TrimSuffix(val, rateSuffixBits)
+	default:
+		isBits = false
+	}
+	res, err := strconv.ParseFloat(val, rateFloatBitSize)
+	if err != nil {
+		return err
+	}
+	min := rateMinPackets
+	if isBits {
+		min = rateMinBits
+	}
+	res *= multiplier
+	if math.IsNaN(res) || math.IsInf(res, 0) || res < min {
+		return errRateInvalid
+	}
+	r.IsSet = true
+	r.Value = res
+	r.IsBits = isBits
+	return nil
+}
+
+// Flag gets the command-line flag used for Rate.
+func (*Rate) Flag() string {
+	return rateFlag
+}
+
+// Help gets the command-line help for Rate.
+func (*Rate) Help() string {
+	return rateHelp
+}
+
+// gets the rate in packets per second for packets of a size
+func (r *Rate) packetsPerSecond(size PacketSize) float64 {
+	if !r.IsBits {
+		return r.Value
+	}
+	return r.Value / float64((int(size)+rateHeaderSize)*rateBitsPerByte)
+}
@@ -0,0 +1,65 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// RateLimit constants for the responder.
+	rateLimitFlag = "r"
+	rateLimitHelp = "Set the maximum number of replies sent per second, where\n" +
+		"requests over the limit are dropped like a rate-limiting router.\n" +
+		"If unset, replies are not rate limited."
+	rateLimitInvalid = "rate limit must be greater than 0"
+)
+
+var (
+	// error for invalid rate limit
+	errRateLimitInvalid = errors.New(rateLimitInvalid)
+)
+
+// RateLimit is a wrapper around a boolean and unsigned integer
+// to use for command-line argument flag parsing.
+type RateLimit struct {
+	IsSet bool
+	Value uint32
+}
+
+// Init initializes a RateLimit instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*RateLimit) Init() {
+}
+
+// String is used to format RateLimit's value and is required
+// to satisfy the flag.Value interface.
+func (r *RateLimit) String() string {
+	return fmt.Sprintf("set=%v, value=%v", r.IsSet, r.Value)
+}
+
+// Set will initialize RateLimit's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (r *RateLimit) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res <= 0 {
+		return errRateLimitInvalid
+	}
+	r.IsSet = true
+	r.Value = uint32(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for RateLimit.
+func (*RateLimit) Flag() string {
+	return rateLimitFlag
+}
+
+// Help gets the command-line help for RateLimit.
+func (*RateLimit) Help() string {
+	return rateLimitHelp
+}
@@ -3,6 +3,7 @@ package ping
 import (
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"golang.org/x/net/icmp"
@@ -10,50 +11,224 @@ import (
 	"golang.org/x/net/ipv6"
 )
 
+const (
+	// ttl and tos of a reply when the system does not report them
+	ttlUnknown = -1
+	tosUnknown = -1
+	// control messages received with icmp replies, where
+	// the ttl and tos of ipv4 replies are in their header
+	ipv4ControlFlags = ipv4.FlagInterface
+	ipv6ControlFlags = ipv6.FlagInterface | ipv6.FlagHopLimit | ipv6.FlagTrafficClass
+)
+
+const (
+	replyQueueSize = 1024 // replies read but not yet processed
+)
+
 var (
 	readTimeout = time.Second // timeout for reading icmp packets
+	// replies released once processed, reused along with
+	// their bytes so reading a reply does not allocate
+	replyPool = sync.Pool{New: func() interface{} { return new(replyPacket) }}
 )
 
-// sends an ICMP "echo request" to a host for a particular
-// sequence using the Ping request
+// represents a packet read from the connection
+type replyPacket struct {
+	bytes      []byte    // icmp message or udp datagram
+	peer       net.Addr  // address the packet was sent from
+	ifIndex    int       // index of the interface it arrived on, 0 if unknown
+	ttl        int       // ttl (ipv4) / hop limit (ipv6), ttlUnknown if unknown
+	tos        int       // tos (ipv4) / traffic class (ipv6), tosUnknown if unknown
+	recvTime   time.Time // time the packet was received
+	kernelTime bool      // if the receive time is a kernel timestamp
+}
+
+// receives the replies to the "echo requests" sent
+// to a host using the Ping request, passing them
+// on to the processing loop
 func (p *Ping) receiver(done <-chan bool, errors chan<- error) {
 	defer p.waitGroup.Done()
+	buffer := make([]byte, icmpPacketMaxSize) // assuming max packet, reused for every read
+	oob := make([]byte, p.oobSize())          // control messages, reused for every read
 	for {
 		select {
 		case <-done:
 			return
 		default:
-			buffer := make([]byte, icmpPacketMaxSize)           // assuming max packet
 			p.conn.SetReadDeadline(time.Now().Add(readTimeout)) // avoid blocking read (might want to clean up)
-			n, _, err := p.conn.ReadFrom(buffer)                // read incoming icmp packets
+			reply, err := p.receive(buffer, oob)                // read incoming packets
 			if err, ok := err.(net.Error); ok && err.Timeout() {
 				continue // timed out, try to read again
 			}
 			if err != nil {
-				go func() { errors <- fmt.Errorf("failed to read: %v", err) }()
+				go func() { errors <- fmt.Errorf("failed to read: %w", err) }()
 				return
 			}
-			// handle reply
-			recvTime := time.Now()
-			p.waitGroup.Add(1)
-			go p.handleReply(buffer[:n], recvTime)
-			if bool(p.Flood) {
-				// update count of recv packages since last reset
-				go func() {
-					p.floodRecvMux.Lock()
-					defer p.floodRecvMux.Unlock()
-					p.floodRecv++
-				}()
+			if reply == nil {
+				continue // not meant for the Ping, so ignore it
+			}
+			select {
+			case p.replies <- reply:
+			case <-done:
+				return
 			}
 		}
 	}
 }
 
-// handles the reply depending on its type
-func (p *Ping) handleReply(reply []byte, recvTime time.Time) {
+// processes the replies read by the receiver and the wait time
+// expiries of sent sequences in a single loop, so no goroutine
+// or timer is needed for each sent or received packet
+func (p *Ping) processor(done <-chan bool) {
 	defer p.waitGroup.Done()
+	timer := time.NewTimer(0)
+	for {
+		// expire sequences whose wait time passed, and
+		// wait until the next one or a reply arrives
+		wait, pending := p.scheduler.expire(time.Now(), p.expireWaitTime)
+		if !timer.Stop() {
+			select {
+			case <-timer.C: // drain the fired timer
+			default:
+			}
+		}
+		if pending {
+			timer.Reset(wait)
+		}
+		select {
+		case <-done:
+			timer.Stop()
+			return
+		case reply := <-p.replies:
+			p.process(reply)
+		case <-p.scheduler.wake: // earlier expiry scheduled
+		case <-timer.C:
+		}
+	}
+}
+
+// receives a reply from the connection, read into the buffer and
+// oob, returning a nil reply if it arrived on another interface,
+// where the reply holds a copy of the bytes read, as the buffer
+// is reused for every read
+func (p *Ping) receive(buffer, oob []byte) (*replyPacket, error) {
+	reply, err := p.read(buffer, oob)
+	if err != nil {
+		return nil, err
+	}
+	if p.iface != nil && reply.ifIndex != 0 && reply.ifIndex != p.iface.Index {
+		replyPool.Put(reply)
+		return nil, nil // arrived on another interface
+	}
+	// the transmit timestamp of a request is queued
+	// before its reply arrives, so apply it first
+	p.applyTxTimestamps()
+	return reply, nil
+}
+
+// processes a reply received for the probe, releasing it
+// once handled, so it must not be kept past the call
+func (p *Ping) process(reply *replyPacket) {
+	if p.Probe.Protocol == probeUDP {
+		p.handleUDPReply(reply)
+	} else {
+		p.handleReply(reply)
+	}
+	replyPool.Put(reply)
+}
+
+// reads a packet from the connection into the buffer, along
+// with its ttl, tos and interface for icmp connections, and its
+// kernel receive timestamp if enabled, where the control messages
+// are read into the oob buffer, and the packet is copied into a
+// reply from the pool
+func (p *Ping) read(buffer, oob []byte) (*replyPacket, error) {
+	n, oobn, peer, err := readMsg(p.conn, buffer, oob)
+	if err != nil {
+		return nil, err
+	}
+	reply := replyPool.Get().(*replyPacket)
+	*reply = replyPacket{bytes: reply.bytes[:0], ttl: ttlUnknown, tos: tosUnknown}
+	reply.peer = peer
+	reply.recvTime = time.Now()
+	oob = oob[:oobn]
+	if recvTime, ok := parseRxTimestamp(oob); ok {
+		reply.recvTime = recvTime
+		reply.kernelTime = true
+	}
+	switch {
+	case p.ipv4Conn != nil:
+		n = p.parseIPv4(buffer[:n], oob, reply)
+	case p.ipv6Conn != nil:
+		cm := ipv6.ControlMessage{}
+		if cm.Parse(oob) == nil {
+			reply.ifIndex, reply.ttl, reply.tos = cm.IfIndex, cm.HopLimit, cm.TrafficClass
+		}
+	}
+	reply.bytes = append(reply.bytes, buffer[:n]...)
+	return reply, nil
+}
+
+// reads a packet and its control messages from a connection,
+// where only ip and udp connections have control messages
+func readMsg(conn net.PacketConn, buffer, oob []byte) (n, oobn int, peer net.Addr, err error) {
+	switch conn := conn.(type) {
+	case *net.IPConn:
+		var addr *net.IPAddr
+		n, oobn, _, addr, err = conn.ReadMsgIP(buffer, oob)
+		return n, oobn, addr, err
+	case *net.UDPConn:
+		var addr *net.UDPAddr
+		n, oobn, _, addr, err = conn.ReadMsgUDP(buffer, oob)
+		return n, oobn, addr, err
+	default:
+		n, peer, err = conn.ReadFrom(buffer)
+		return n, 0, peer, err
+	}
+}
+
+// parses an IPv4 datagram read from the icmp connection, moving
+// its payload to the start of the datagram and setting the reply's
+// interface, ttl and tos, returning the size of the payload
+func (p *Ping) parseIPv4(datagram, oob []byte, reply *replyPacket) int {
+	// the ip connection does not strip the header when reading messages
+	header, err := ipv4.ParseHeader(datagram)
+	if err != nil || header.Len > len(datagram) {
+		return 0 // failed to parse header, so handle an empty reply
+	}
+	reply.ttl, reply.tos = header.TTL, header.TOS
+	cm := ipv4.ControlMessage{}
+	if cm.Parse(oob) == nil {
+		reply.ifIndex = cm.IfIndex
+	}
+	return copy(datagram, datagram[header.Len:])
+}
+
+// notifies the flood or adaptive sender of a received
+// packet, which allows it to send another request
+func (p *Ping) notifyReceived() {
+	select {
+	case p.recvNotify <- struct{}{}:
+	default: // the sender is already notified of enough packets
+	}
+}
+
+// drains the notifications of received packets
+// the sender has not waited for
+func (p *Ping) drainReceived() {
+	for {
+		select {
+		case <-p.recvNotify:
+		default:
+			return
+		}
+	}
+}
+
+// handles the reply depending on its type
+func (p *Ping) handleReply(reply *replyPacket) {
 	// attempt to parse message
-	message, err := icmp.ParseMessage(p.proto, reply)
+	message, err := icmp.ParseMessage(p.proto, reply.bytes)
 	if err != nil {
 		return // failed to parse message, so ignore it
 	}
@@ -76,7 +251,10 @@ func (p *Ping) handleReply(reply []byte, recvTime time.Time) {
 				return // failed to parse header, ignore
 			}
 		}
-		p.handleEchoTimeExceeded(reply, recvTime, header, body)
+		if !p.isHostDst(header) {
+			return // about a packet sent to another host, ignore
+		}
+		p.handleEchoTimeExceeded(reply, header, body)
 	case ipv4.ICMPTypeDestinationUnreachable, ipv6.ICMPTypeDestinationUnreachable:
 		body, ok := message.Body.(*icmp.DstUnreach)
 		if !ok || body == nil {
@@ -93,24 +271,16 @@ func (p *Ping) handleReply(reply []byte, recvTime time.Time) {
 				return // failed to parse header, ignore
 			}
 		}
-		p.handleEchoDstUnreachable(reply, recvTime, header, body)
+		if !p.isHostDst(header) {
+			return // about a packet sent to another host, ignore
+		}
+		p.handleEchoDstUnreachable(reply, header, body)
 	case ipv4.ICMPTypeEchoReply, ipv6.ICMPTypeEchoReply:
 		body, ok := message.Body.(*icmp.Echo)
 		if !ok || body == nil {
 			return // failed to parse body, ignore
 		}
-		if p.isIPv4 {
-			header, err = ipv4.ParseHeader(body.Data)
-			if header == nil || err != nil {
-				return // failed to parse header, ignore
-			}
-		} else {
-			header, err = ipv6.ParseHeader(body.Data)
-			if header == nil || err != nil {
-				return // failed to parse header, ignore
-			}
-		}
-		p.handleEchoReply(reply, recvTime, header, body)
+		p.handleEchoReply(reply, body)
 	default:
 		return // unknown or unhandled type, so ignoring
 	}
@@ -121,9 +291,9 @@ func (p *Ping) handleReply(reply []byte, recvTime time.Time) {
 // a non-nil *ipv4.Header or non-nil *ipv6.Header
 // for now, there is no validation to check
 // for associated sequence / if we sent a request
-func (p *Ping) handleEchoTimeExceeded(reply []byte, recvTime time.Time, header interface{}, body *icmp.TimeExceeded) {
-	fmt.Printf("%v bytes from %v: Time to live exceeded\n%v\n",
-		len(reply), p.hostAddr.String(), header)
+func (p *Ping) handleEchoTimeExceeded(reply *replyPacket, header interface{}, body *icmp.TimeExceeded) {
+	p.printReply("%v bytes from %v: Time to live exceeded\n%v\n",
+		len(reply.bytes), p.describePeer(reply.peer), header)
 }
 
 // handles an IPv4 or IPv6 echo host unreachable reply
@@ -131,37 +301,82 @@ func (p *Ping) handleEchoTimeExceeded(reply []byte, recvTime time.Time, header i
 // a non-nil *ipv4.Header or non-nil *ipv6.Header
 // for now, there is no validation to check
 // for associated sequence / if we sent a request
-func (p *Ping) handleEchoDstUnreachable(reply []byte, recvTime time.Time, header interface{}, body *icmp.DstUnreach) {
-	fmt.Printf("%v bytes from %v: Destination unreachable\n%v\n",
-		len(reply), p.hostAddr.String(), header)
+func (p *Ping) handleEchoDstUnreachable(reply *replyPacket, header interface{}, body *icmp.DstUnreach) {
+	p.printReply("%v bytes from %v: Destination unreachable\n%v\n",
+		len(reply.bytes), p.describePeer(reply.peer), header)
 }
 
-// handles an IPv4 or IPv6 echo reply, where the
-// header interface argument is either
-// a non-nil *ipv4.Header or non-nil *ipv6.Header
-func (p *Ping) handleEchoReply(reply []byte, recvTime time.Time, header interface{}, body *icmp.Echo) {
+// handles an IPv4 or IPv6 echo reply
+func (p *Ping) handleEchoReply(reply *replyPacket, body *icmp.Echo) {
 	// validate
-	if body.ID != p.id {
+	if body.ID != p.id || !hasCookie(body.Data, p.cookie) {
 		return // echo request not sent by our client, so ignore response
 	}
+	if !p.isHostPeer(reply.peer) {
+		return // not sent by the host, so ignore spoofed or foreign response
+	}
+	meta, hasMeta := parseMetadata(body.Data)
+	packet, ok := p.receiveEcho(reply, body, meta, hasMeta)
+	if !ok || bool(p.Quiet) {
+		return
+	}
+	var tos string
+	if p.TOS.IsSet {
+		tos = fmt.Sprintf(" tos=%#x", packet.receivedTOS) // to detect re-marking
+	}
+	fmt.Fprintf(p.output(), "%v bytes from %v: icmp_seq=%v ttl=%v%v time=%v%v\n",
+		len(reply.bytes), p.describePeer(reply.peer), packet.seq, packet.receivedTTL, tos, packet.roundtripTime, p.describeLate(&packet))
+	// compare with the payload sent while it is kept,
+	// which finds the wrong byte, or check the checksum
+	if packet.payload != nil {
+		printWrongByte(p.output(), packet.payload, body.Data, 0)
+	} else if hasMeta && !meta.intact {
+		fmt.Fprintln(p.output(), "wrong data checksum")
+	}
+}
+
+// marks the packet an echo reply answers as received, returning a
+// copy of it for the output, which is written once sentMux is
+// released, or false if the reply answers no packet in flight
+func (p *Ping) receiveEcho(reply *replyPacket, body *icmp.Echo, meta metadata, hasMeta bool) (icmpPacket, bool) {
 	p.sentMux.Lock()
 	defer p.sentMux.Unlock()
+	// map the on-wire sequence back to the sequence sent,
+	// or take it from the metadata if intact
+	seq, ok := unwrapSeq(body.Seq, p.latestSeq)
+	if hasMeta && meta.intact && meta.seq <= p.latestSeq && wireSeq(meta.seq) == body.Seq {
+		seq, ok = meta.seq, true
+	}
+	if !ok {
+		return icmpPacket{}, false // not sent yet, so ignore response
+	}
+	packet, ok := p.sent.get(seq)
+	if !ok && hasMeta && meta.intact && p.sent.receiveEvicted(seq) {
+		// evicted by a later sequence, so the send time in the
+		// metadata, which is wall clock time, is all that is left
+		// to measure the late reply with, where the window records
+		// it so a duplicate is ignored
+		packet, ok = &icmpPacket{seq: seq, sendTime: meta.sendTime, waitTimeExceeded: true,
+			segment: len(p.segments) - 1}, true
+	}
 	// only handle new valid sequence numbers
-	if packet, ok := p.sent[body.Seq]; ok && !packet.received {
-		packet.received = true
-		packet.receiveTime = recvTime
-		packet.roundtripTime = recvTime.Sub(packet.sendTime)
-		// get ttl from header
-		if p.isIPv4 {
-			packet.receivedTTL = header.(*ipv4.Header).TTL
-		} else {
-			packet.receivedTTL = header.(*ipv6.Header).HopLimit
-		}
-		// only print if wait time not exceeded
-		// note: currently not checking integrity of payload
-		if !packet.waitTimeExceeded {
-			fmt.Printf("%v bytes from %v: icmp_seq=%v ttl=%v time=%v\n",
-				len(reply), p.hostAddr.String(), body.Seq, packet.receivedTTL, packet.roundtripTime)
-		}
+	if !ok || packet.received {
+		return icmpPacket{}, false
+	}
+	p.markReceived(packet, reply.recvTime, reply.kernelTime)
+	packet.receivedTTL = reply.ttl
+	packet.receivedTOS = reply.tos
+	return *packet, true
+}
+
+// gets the size of the buffer for the control messages of a reply
+func (p *Ping) oobSize() int {
+	size := timestampOOBSize
+	switch {
+	case p.ipv4Conn != nil:
+		size += len(ipv4.NewControlMessage(ipv4ControlFlags))
+	case p.ipv6Conn != nil:
+		size += len(ipv6.NewControlMessage(ipv6ControlFlags))
 	}
+	return size
 }
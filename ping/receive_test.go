@@ -0,0 +1,73 @@
+package ping
+
+import (
+	"testing"
+)
+
+// sequences sent between timing the processing of their replies
+const benchBatchSize = 1024
+
+func TestLateReplyCountedOnce(t *testing.T) {
+	p := newTestPing(t, false)
+	conn := p.conn.(*echoConn)
+	if err := p.send(0); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	request := conn.requests[0]
+	// evict the first sequence with a window of later ones
+	conn.requests, conn.discard = nil, true
+	for seq := uint64(1); seq <= windowSize; seq++ {
+		if err := p.send(seq); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	// the reply to the evicted sequence arrives twice
+	conn.requests = [][]byte{request, request}
+	buffer := make([]byte, icmpPacketMaxSize)
+	oob := make([]byte, p.oobSize())
+	for range conn.requests {
+		reply, err := p.receive(buffer, oob)
+		if err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+		p.process(reply)
+	}
+	stats := p.statsSnapshot()
+	if stats.received != 1 || stats.exceeded != 1 {
+		t.Errorf("expected the late reply to be counted once, got %v received and %v late",
+			stats.received, stats.exceeded)
+	}
+}
+
+func BenchmarkProcess(b *testing.B) {
+	p := newTestPing(b, false)
+	buffer := make([]byte, icmpPacketMaxSize)
+	oob := make([]byte, p.oobSize())
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i += benchBatchSize {
+		batch := b.N - i
+		if batch > benchBatchSize {
+			batch = benchBatchSize
+		}
+		// send the requests of the batch without timing them
+		b.StopTimer()
+		for seq := i; seq < i+batch; seq++ {
+			if err := p.send(uint64(seq)); err != nil {
+				b.Fatalf("failed to send: %v", err)
+			}
+		}
+		b.StartTimer()
+		for j := 0; j < batch; j++ {
+			reply, err := p.receive(buffer, oob)
+			if err != nil {
+				b.Fatalf("failed to receive: %v", err)
+			}
+			p.process(reply)
+		}
+	}
+	b.StopTimer()
+	if received := p.statsSnapshot().received; received != uint64(b.N) {
+		b.Fatalf("expected %v replies to be received, got %v", b.N, received)
+	}
+}
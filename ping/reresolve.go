@@ -0,0 +1,155 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	// Reresolve constants for re-resolving the host name.
+	reresolveFlag = "R"
+	reresolveHelp = "Set the number of seconds between re-resolving the host\n" +
+		"name, which is also re-resolved after 3 missing replies in a\n" +
+		"row. If the host no longer resolves to the address pinged, the\n" +
+		"first of its new addresses in the same family is pinged, and\n" +
+		"the statistics are split by address. If unset, the host name\n" +
+		"is only resolved at the start."
+	reresolveInvalid       = "re-resolve interval must be greater than 0"
+	reresolveLossThreshold = 3 // missing replies in a row that trigger re-resolving
+)
+
+var (
+	// error for invalid re-resolve interval
+	errReresolveInvalid = errors.New(reresolveInvalid)
+)
+
+// Reresolve is a wrapper around a boolean and a time.Duration
+// to use for command-line argument flag parsing.
+type Reresolve struct {
+	IsSet bool
+	Value time.Duration
+}
+
+// Init initializes a Reresolve instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Reresolve) Init() {
+}
+
+// String is used to format Reresolve's value and is required
+// to satisfy the flag.Value interface.
+func (r *Reresolve) String() string {
+	return fmt.Sprintf("set=%v, value=%v", r.IsSet, r.Value)
+}
+
+// Set will initialize Reresolve's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (r *Reresolve) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res <= 0 {
+		return errReresolveInvalid
+	}
+	r.IsSet = true
+	r.Value = time.Second * time.Duration(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Reresolve.
+func (*Reresolve) Flag() string {
+	return reresolveFlag
+}
+
+// Help gets the command-line help for Reresolve.
+func (*Reresolve) Help() string {
+	return reresolveHelp
+}
+
+// represents the statistics of the probes sent to one address
+// of the host, where each address the host name resolves to
+// during the run starts a new segment
+type addressSegment struct {
+	addr  *net.IPAddr // address the probes were sent to
+	start time.Time   // time the address started being pinged
+	stats rttStats    // statistics of the probes sent to the address
+}
+
+// re-resolves the host name at every re-resolve interval,
+// and after missing replies in a row
+func (p *Ping) reresolver(done <-chan bool) {
+	defer p.waitGroup.Done()
+	ticker := time.NewTicker(p.Reresolve.Value)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		case <-p.lossNotify:
+		}
+		p.reresolve()
+	}
+}
+
+// resolves the host name again, switching to the first of its
+// addresses in the same family if it no longer resolves to
+// the address pinged, which starts a new segment
+func (p *Ping) reresolve() {
+	network := ipv6Network
+	if p.isIPv4 {
+		network = ipv4Network // the connection only reaches one family
+	}
+	addrs, err := p.lookupAll(p.ctx, network)
+	now := time.Now()
+	if p.ctx.Err() != nil {
+		return // ping is stopping, so ignore the result
+	}
+	if err != nil {
+		fmt.Fprintf(p.output(), "%v: %v\n", now.Format(summaryTimeFormat), err)
+		return // keep pinging the current address
+	}
+	p.sentMux.Lock()
+	previous := p.hostAddr
+	for _, addr := range addrs {
+		if addr.IP.Equal(previous.IP) {
+			p.sentMux.Unlock()
+			return // still an address of the host
+		}
+	}
+	p.hostAddr = addrs[0]
+	p.segments = append(p.segments, addressSegment{addr: addrs[0], start: now})
+	p.sentMux.Unlock()
+	fmt.Fprintf(p.output(), "%v: %v changed address from %v to %v\n",
+		now.Format(summaryTimeFormat), p.HostName, previous.String(), addrs[0].String())
+}
+
+// counts a missing reply, notifying the re-resolver after missing
+// replies in a row, must be called with sentMux held
+func (p *Ping) countLoss() {
+	p.lossesInRow++
+	if p.lossesInRow < reresolveLossThreshold {
+		return
+	}
+	p.lossesInRow = 0 // notify again after the next ones
+	select {
+	case p.lossNotify <- struct{}{}:
+	default: // already notified
+	}
+}
+
+// prints the statistics of each address the host was pinged
+// at, if it changed, must be called with sentMux held
+func (p *Ping) printSegments() {
+	if len(p.segments) < 2 {
+		return // a single address, so the stats are the same
+	}
+	for _, segment := range p.segments {
+		printSummary(p.output(), fmt.Sprintf("%v since %v", segment.addr.String(), segment.start.Format(summaryTimeFormat)),
+			segment.stats)
+	}
+}
@@ -0,0 +1,135 @@
+package ping
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// makes a ping of a host name answered by a resolver,
+// initialized for its probe
+func newResolvedPing(t *testing.T, resolver Resolver, output *bytes.Buffer, opts ...Option) *Ping {
+	t.Helper()
+	c := DefaultConfig("ping.test")
+	c.Numeric = true
+	c.Resolver = resolver
+	c.Output = output
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			t.Fatalf("invalid option: %v", err)
+		}
+	}
+	p := &Ping{Config: c, stop: make(chan struct{})}
+	p.writer = &syncWriter{w: p.output()} // probes may print at the same time, as in start()
+	if err := p.init(); err != nil {
+		t.Fatalf("failed to initialize ping: %v", err)
+	}
+	t.Cleanup(p.close)
+	return p
+}
+
+// adds a sent packet, which is received or expires
+func resolveSent(p *Ping, seq uint64, received bool) {
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	packet := &icmpPacket{seq: seq, sendTime: time.Now()}
+	p.addSent(packet)
+	if received {
+		p.markReceived(packet, time.Now(), false)
+	} else {
+		p.markExpired(packet)
+	}
+}
+
+func TestReresolveSplitsSegments(t *testing.T) {
+	resolver := &staticResolver{}
+	resolver.setAddrs("127.0.0.1")
+	var output bytes.Buffer
+	p := newResolvedPing(t, resolver, &output, WithProbe(probeTCP+":7"), WithReresolve(time.Hour))
+	resolveSent(p, 0, true)
+	p.reresolve() // the host still resolves to the address pinged
+	if len(p.segments) != 1 || output.Len() != 0 {
+		t.Fatalf("expected no change for the same address, got %v segments and:\n%v", len(p.segments), output.String())
+	}
+	resolver.setAddrs("::1", "127.0.0.2", "127.0.0.3")
+	p.reresolve()
+	if !p.hostAddr.IP.Equal(net.ParseIP("127.0.0.2")) {
+		t.Errorf("expected the first address in the same family, got %v", p.hostAddr)
+	}
+	if !strings.Contains(output.String(), "ping.test changed address from 127.0.0.1 to 127.0.0.2") {
+		t.Errorf("expected the change of address, got:\n%v", output.String())
+	}
+	resolveSent(p, 1, true)
+	resolveSent(p, 2, false)
+	if len(p.segments) != 2 {
+		t.Fatalf("expected 2 segments, got %v", len(p.segments))
+	}
+	if got := p.segments[0].stats; got.transmitted != 1 || got.received != 1 {
+		t.Errorf("expected 1/1 packets at the first address, got %v/%v", got.received, got.transmitted)
+	}
+	if got := p.segments[1].stats; got.transmitted != 2 || got.received != 1 || got.expired != 1 {
+		t.Errorf("expected 1/2 packets at the second address, got %v/%v", got.received, got.transmitted)
+	}
+	output.Reset()
+	p.sentMux.Lock()
+	p.printSegments()
+	p.sentMux.Unlock()
+	for _, addr := range []string{"127.0.0.1", "127.0.0.2"} {
+		if !strings.Contains(output.String(), addr+" since ") {
+			t.Errorf("expected the statistics of %v, got:\n%v", addr, output.String())
+		}
+	}
+}
+
+func TestReresolveKeepsAddressOnError(t *testing.T) {
+	resolver := &staticResolver{}
+	resolver.setAddrs("127.0.0.1")
+	var output bytes.Buffer
+	p := newResolvedPing(t, resolver, &output, WithProbe(probeTCP+":7"), WithReresolve(time.Hour))
+	resolver.err = &net.DNSError{Err: "server misbehaving", Name: "ping.test", IsTemporary: true}
+	p.reresolve()
+	if len(p.segments) != 1 || !p.hostAddr.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected the address to be kept, got %v in %v segments", p.hostAddr, len(p.segments))
+	}
+	if !strings.Contains(output.String(), "server misbehaving") {
+		t.Errorf("expected the resolver error, got:\n%v", output.String())
+	}
+}
+
+func TestCountLossNotifies(t *testing.T) {
+	resolver := &staticResolver{}
+	resolver.setAddrs("127.0.0.1")
+	p := newResolvedPing(t, resolver, &bytes.Buffer{}, WithProbe(probeTCP+":7"), WithReresolve(time.Hour))
+	notified := func() bool {
+		select {
+		case <-p.lossNotify:
+			return true
+		default:
+			return false
+		}
+	}
+	seq := uint64(0)
+	lose := func(n int) {
+		for i := 0; i < n; i++ {
+			resolveSent(p, seq, false)
+			seq++
+		}
+	}
+	lose(reresolveLossThreshold - 1)
+	resolveSent(p, seq, true) // a reply breaks the missing replies in a row
+	seq++
+	lose(reresolveLossThreshold - 1)
+	if notified() {
+		t.Errorf("expected no notification before %v missing replies in a row", reresolveLossThreshold)
+	}
+	lose(1)
+	if !notified() {
+		t.Errorf("expected a notification after %v missing replies in a row", reresolveLossThreshold)
+	}
+	lose(reresolveLossThreshold)
+	if !notified() {
+		t.Errorf("expected a notification after the next %v missing replies", reresolveLossThreshold)
+	}
+}
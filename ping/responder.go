@@ -0,0 +1,309 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// constants for responders
+	responderProbeInvalid = "responder only supports icmp and udp:port probes"
+	responderPercentMax   = 100
+	responderSessionIdle  = 10 * time.Minute // time after which a silent session is forgotten
+	responderSweepPeriod  = time.Minute      // time between looking for idle sessions
+	responderIPv4Datagram = "udp4"           // network of ipv4 icmp datagram sockets
+	responderIPv6Datagram = "udp6"           // network of ipv6 icmp datagram sockets
+)
+
+var (
+	// error for a probe the responder cannot reflect
+	errResponderProbeInvalid = errors.New(responderProbeInvalid)
+)
+
+// Responder is used to represent a responder that reflects
+// "echo requests" back to their sender, answering ICMP echo
+// requests in userspace or reflecting UDP probes.
+// Replies can be impaired to reproduce lossy or slow paths.
+type Responder struct {
+	Probe     Probe     // probe to respond to (icmp, udp:port)
+	Delay     Delay     // delay before each reply
+	Loss      Loss      // percentage of requests dropped
+	Corrupt   Corrupt   // percentage of replies corrupted
+	RateLimit RateLimit // if set, max replies per second
+	Address   string    // address to listen on, all IPv4 addresses if empty
+	tokens    float64   // replies that can be sent under the rate limit
+	refilled  time.Time // time the tokens were last refilled
+}
+
+// identifies a session of a sender
+type responderSession struct {
+	addr    string // address of the sender
+	session uint32 // session id of the sender
+}
+
+// represents the datagrams received from a session of a sender
+type responderSessionState struct {
+	received uint64    // datagrams received
+	lastSeen time.Time // time the latest datagram was received
+}
+
+// Validate checks if the Responder is valid,
+// returning a non-nil error if invalid.
+// Should be called before calling Start().
+func (r *Responder) Validate() error {
+	if r.Probe.Protocol != probeICMP && r.Probe.Protocol != probeUDP {
+		return errResponderProbeInvalid
+	}
+	if r.Address != "" && r.ip() == nil {
+		return errHostInvalid
+	}
+	return nil
+}
+
+// gets the ip address the Responder listens on, without the
+// zone of an IPv6 link-local address, or nil if it listens on
+// all IPv4 addresses or the address is invalid
+func (r *Responder) ip() net.IP {
+	host, zone := r.Address, ""
+	if i := strings.LastIndex(host, "%"); i >= 0 {
+		host, zone = host[:i], host[i+1:]
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && zone != "" && ip.To4() != nil {
+		return nil // only IPv6 addresses have zones
+	}
+	return ip
+}
+
+// Start begins reflecting the "echo requests"
+// sent to the Responder, until an error occurs.
+func (r *Responder) Start() error {
+	if err := r.Validate(); err != nil {
+		return err
+	}
+	r.initRateLimit()
+	if r.Probe.Protocol == probeUDP {
+		return r.respondUDP()
+	}
+	return r.respondICMP()
+}
+
+// fills the tokens of the rate limit, so the first
+// second of replies can be sent at once
+func (r *Responder) initRateLimit() {
+	r.tokens = float64(r.RateLimit.Value)
+	r.refilled = time.Now()
+}
+
+// reflects UDP probes back to their sender
+func (r *Responder) respondUDP() error {
+	address := net.JoinHostPort(r.Address, strconv.Itoa(int(r.Probe.Port)))
+	conn, err := net.ListenPacket(udpNetwork, address)
+	if err != nil {
+		return &SocketError{Op: "get udp conn", Err: err}
+	}
+	defer conn.Close()
+	fmt.Printf("RESPONDER %v: udp port %v\n", conn.LocalAddr().String(), r.Probe.Port)
+	return r.serveUDP(conn)
+}
+
+// reflects the UDP probes read from a connection,
+// until reading from it fails
+func (r *Responder) serveUDP(conn net.PacketConn) error {
+	// datagrams received from each session, so the sender can
+	// infer in which direction datagrams were lost
+	sessions := make(map[responderSession]*responderSessionState)
+	swept := time.Now()
+	buffer := make([]byte, udpDatagramMaxSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return fmt.Errorf("failed to read: %w", err)
+		}
+		now := time.Now()
+		if now.Sub(swept) >= responderSweepPeriod {
+			evictIdleSessions(sessions, now)
+			swept = now
+		}
+		header, ok := parseUDPHeader(buffer[:n])
+		if !ok {
+			continue // not a probe, so ignore it
+		}
+		key := responderSession{addr: addr.String(), session: header.session}
+		session, ok := sessions[key]
+		if !ok {
+			session = &responderSessionState{}
+			sessions[key] = session
+		}
+		session.received++
+		session.lastSeen = now
+		header.responded = session.received
+		reply := make([]byte, n)
+		copy(reply, buffer[:n])
+		header.marshal(reply)
+		// only the payload after the header is corrupted,
+		// so the sender can still match the reply
+		if r.shouldCorrupt() {
+			corruptBytes(reply, udpHeaderSize)
+		}
+		r.reply(conn, reply, addr)
+	}
+}
+
+// forgets the sessions that were idle for the idle time, so the
+// memory of a long-running responder stays bounded, where a session
+// that resumes later starts counting its datagrams again
+func evictIdleSessions(sessions map[responderSession]*responderSessionState, now time.Time) {
+	for key, session := range sessions {
+		if now.Sub(session.lastSeen) >= responderSessionIdle {
+			delete(sessions, key)
+		}
+	}
+}
+
+// answers ICMP echo requests in userspace, note that the
+// kernel may also answer them unless configured not to
+func (r *Responder) respondICMP() error {
+	conn, proto, err := r.listenICMP()
+	if err != nil {
+		return &SocketError{Op: "get packet conn", Err: err}
+	}
+	defer conn.Close()
+	fmt.Printf("RESPONDER %v: icmp\n", conn.LocalAddr().String())
+	return r.serveICMP(conn, proto)
+}
+
+// listens for ICMP echo requests on a raw socket, or on a
+// datagram socket where the system delivers echo requests to
+// them and a raw socket is not permitted, returning the
+// connection and the protocol of its messages
+func (r *Responder) listenICMP() (*icmp.PacketConn, int, error) {
+	network, datagram, bindAddress, proto := ipv4ICMPNetwork, responderIPv4Datagram, ipv4BindAddress, ianaProtocolIPv4ICMP
+	if ip := r.ip(); ip != nil && ip.To4() == nil {
+		network, datagram, bindAddress, proto = ipv6ICMPNetwork, responderIPv6Datagram, ipv6BindAddress, ianaProtocolIPv6ICMP
+	}
+	if r.Address != "" {
+		bindAddress = r.Address
+	}
+	conn, err := icmp.ListenPacket(network, bindAddress)
+	if err != nil && responderDatagramICMP && errors.Is(err, os.ErrPermission) {
+		conn, err = icmp.ListenPacket(datagram, bindAddress)
+	}
+	return conn, proto, err
+}
+
+// answers the ICMP echo requests read from a connection,
+// until reading from it fails
+func (r *Responder) serveICMP(conn net.PacketConn, proto int) error {
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if proto == ianaProtocolIPv6ICMP {
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	buffer := make([]byte, icmpPacketMaxSize)
+	for {
+		n, addr, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return fmt.Errorf("failed to read: %w", err)
+		}
+		message, err := icmp.ParseMessage(proto, buffer[:n])
+		if err != nil || message.Type != requestType {
+			continue // not an echo request, so ignore it
+		}
+		body, ok := message.Body.(*icmp.Echo)
+		if !ok || body == nil {
+			continue // failed to parse body, ignore
+		}
+		// corrupt a copy of the payload before marshalling, so the
+		// checksum is valid and the reply reaches the sender
+		data := make([]byte, len(body.Data))
+		copy(data, body.Data)
+		if r.shouldCorrupt() {
+			corruptBytes(data, echoCorruptOffset(len(data)))
+		}
+		reply := icmp.Message{
+			Type: replyType,
+			Body: &icmp.Echo{ID: body.ID, Seq: body.Seq, Data: data},
+		}
+		bytes, err := reply.Marshal(nil)
+		if err != nil {
+			continue // failed to marshal reply, ignore
+		}
+		r.reply(conn, bytes, addr)
+	}
+}
+
+// sends a reply unless it is dropped by the loss
+// or rate limit, delaying it if set
+func (r *Responder) reply(conn net.PacketConn, reply []byte, addr net.Addr) {
+	if r.shouldDrop() {
+		return
+	}
+	send := func() {
+		conn.WriteTo(reply, addr) // best effort, like the network
+	}
+	if r.Delay > 0 {
+		time.AfterFunc(time.Duration(r.Delay), send)
+		return
+	}
+	send()
+}
+
+// reports if a request should be dropped, either
+// randomly or because the rate limit is exceeded
+func (r *Responder) shouldDrop() bool {
+	if rand.Float64()*responderPercentMax < float64(r.Loss) {
+		return true
+	}
+	if !r.RateLimit.IsSet {
+		return false
+	}
+	// refill tokens for the time since the last reply,
+	// allowing a burst of at most one second of replies
+	now := time.Now()
+	limit := float64(r.RateLimit.Value)
+	r.tokens += now.Sub(r.refilled).Seconds() * limit
+	if r.tokens > limit {
+		r.tokens = limit
+	}
+	r.refilled = now
+	if r.tokens < 1 {
+		return true
+	}
+	r.tokens--
+	return false
+}
+
+// reports if a reply should be corrupted
+func (r *Responder) shouldCorrupt() bool {
+	return rand.Float64()*responderPercentMax < float64(r.Corrupt)
+}
+
+// gets the offset the corruption of an echo payload starts at, past
+// the session cookie, which the sender matches replies with, and past
+// the metadata if the payload has data after it, so a corrupted reply
+// is still counted and reported as wrong data bytes rather than lost
+func echoCorruptOffset(size int) int {
+	if size > metadataSize {
+		return metadataSize
+	}
+	return sessionCookieSize
+}
+
+// flips the bits of a random byte from the offset on,
+// leaving the bytes untouched if there are none
+func corruptBytes(bytes []byte, offset int) {
+	if offset >= len(bytes) {
+		return
+	}
+	bytes[offset+rand.Intn(len(bytes)-offset)] ^= 0xff
+}
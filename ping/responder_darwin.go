@@ -0,0 +1,9 @@
+//go:build darwin
+// +build darwin
+
+package ping
+
+// the system delivers echo requests to ICMP datagram sockets,
+// which need no privileges, so the responder can answer them
+// there when a raw socket is not permitted
+const responderDatagramICMP = true
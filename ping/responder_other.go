@@ -0,0 +1,9 @@
+//go:build !darwin
+// +build !darwin
+
+package ping
+
+// ICMP datagram sockets either do not exist on this system or
+// only receive echo replies (ex. linux), so the responder can
+// only answer echo requests on a raw socket
+const responderDatagramICMP = false
@@ -0,0 +1,225 @@
+package ping
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// constants for responder tests
+	responderTestPayloadSize = 64                     // size of the test probes
+	responderTestWait        = 200 * time.Millisecond // time to wait for a reply
+)
+
+// starts a responder serving a loopback socket,
+// returning the address it listens on
+func listenResponder(t *testing.T, r *Responder, serve func(net.PacketConn) error) net.Addr {
+	t.Helper()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("invalid responder: %v", err)
+	}
+	conn, err := net.ListenPacket(udpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	r.initRateLimit()
+	go serve(conn)
+	return conn.LocalAddr()
+}
+
+// starts a responder serving a loopback socket, returning
+// a connection to send requests to it
+func startResponder(t *testing.T, r *Responder, serve func(net.PacketConn) error) net.Conn {
+	t.Helper()
+	addr := listenResponder(t, r, serve)
+	client, err := net.Dial(udpNetwork, addr.String())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// makes a udp probe for a sequence
+func udpProbe(seq uint64) []byte {
+	datagram := make([]byte, responderTestPayloadSize)
+	for i := range datagram {
+		datagram[i] = byte(i)
+	}
+	header := udpHeader{session: 1, seq: seq, timestamp: time.Now().UnixNano()}
+	header.marshal(datagram)
+	return datagram
+}
+
+// sends requests back-to-back, then reads the replies
+// until none arrive within the wait time
+func exchange(t *testing.T, client net.Conn, requests [][]byte) [][]byte {
+	t.Helper()
+	for _, request := range requests {
+		if _, err := client.Write(request); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	var replies [][]byte
+	buffer := make([]byte, udpDatagramMaxSize)
+	for {
+		client.SetReadDeadline(time.Now().Add(responderTestWait))
+		n, err := client.Read(buffer)
+		if err != nil {
+			return replies
+		}
+		replies = append(replies, append([]byte(nil), buffer[:n]...))
+	}
+}
+
+// sends udp probes to a responder, returning the replies
+func exchangeUDP(t *testing.T, r *Responder, count int) [][]byte {
+	t.Helper()
+	r.Probe = Probe{Protocol: probeUDP}
+	client := startResponder(t, r, r.serveUDP)
+	var requests [][]byte
+	for i := 0; i < count; i++ {
+		requests = append(requests, udpProbe(uint64(i)))
+	}
+	return exchange(t, client, requests)
+}
+
+// counts the bytes that differ between two byte slices of the same size
+func differentBytes(a, b []byte) int {
+	n := 0
+	for i := range a {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
+
+func TestResponderReflectsUDP(t *testing.T) {
+	replies := exchangeUDP(t, &Responder{}, 3)
+	if len(replies) != 3 {
+		t.Fatalf("expected 3 replies, got %v", len(replies))
+	}
+	for i, reply := range replies {
+		header, ok := parseUDPHeader(reply)
+		if !ok || header.seq != uint64(i) || header.responded != uint64(i+1) {
+			t.Errorf("expected reply %v to count %v datagrams, got %+v", i, i+1, header)
+		}
+		if !bytes.Equal(reply[udpHeaderSize:], udpProbe(0)[udpHeaderSize:]) {
+			t.Errorf("expected reply %v to keep the payload", i)
+		}
+	}
+}
+
+func TestResponderDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	start := time.Now()
+	replies := exchangeUDP(t, &Responder{Delay: Delay(delay)}, 1)
+	if len(replies) != 1 {
+		t.Fatalf("expected 1 reply, got %v", len(replies))
+	}
+	if elapsed := time.Since(start) - responderTestWait; elapsed < delay {
+		t.Errorf("expected the reply after %v, got it after %v", delay, elapsed)
+	}
+}
+
+func TestResponderLoss(t *testing.T) {
+	if replies := exchangeUDP(t, &Responder{Loss: 100}, 10); len(replies) != 0 {
+		t.Errorf("expected every request dropped, got %v replies", len(replies))
+	}
+	// the loss is random, so only check it is neither none nor all
+	if replies := exchangeUDP(t, &Responder{Loss: 50}, 200); len(replies) < 50 || len(replies) > 150 {
+		t.Errorf("expected about half the requests dropped, got %v/200 replies", len(replies))
+	}
+}
+
+func TestResponderCorruptUDP(t *testing.T) {
+	replies := exchangeUDP(t, &Responder{Corrupt: 100}, 10)
+	if len(replies) != 10 {
+		t.Fatalf("expected 10 replies, got %v", len(replies))
+	}
+	for i, reply := range replies {
+		if _, ok := parseUDPHeader(reply); !ok {
+			t.Errorf("expected reply %v to keep its header", i)
+		}
+		if n := differentBytes(reply[udpHeaderSize:], udpProbe(0)[udpHeaderSize:]); n != 1 {
+			t.Errorf("expected 1 corrupted payload byte in reply %v, got %v", i, n)
+		}
+	}
+}
+
+func TestResponderRateLimit(t *testing.T) {
+	// only the first second of replies can be sent at once
+	replies := exchangeUDP(t, &Responder{RateLimit: RateLimit{IsSet: true, Value: 5}}, 20)
+	if len(replies) < 5 || len(replies) > 6 {
+		t.Errorf("expected about 5 replies under the rate limit, got %v", len(replies))
+	}
+}
+
+func TestResponderCorruptICMP(t *testing.T) {
+	// an echo request over a udp socket, as the responder only
+	// needs a packet connection, so no privileges are required
+	r := &Responder{Probe: Probe{Protocol: probeICMP}, Corrupt: 100}
+	client := startResponder(t, r, func(conn net.PacketConn) error {
+		return r.serveICMP(conn, ianaProtocolIPv4ICMP)
+	})
+	data := bytes.Repeat([]byte{0x5a}, responderTestPayloadSize)
+	request := icmp.Message{Type: ipv4.ICMPTypeEcho, Body: &icmp.Echo{ID: 7, Seq: 3, Data: data}}
+	b, err := request.Marshal(nil)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	replies := exchange(t, client, [][]byte{b})
+	if len(replies) != 1 {
+		t.Fatalf("expected 1 reply, got %v", len(replies))
+	}
+	message, err := icmp.ParseMessage(ianaProtocolIPv4ICMP, replies[0])
+	if err != nil || message.Type != ipv4.ICMPTypeEchoReply {
+		t.Fatalf("expected an echo reply, got %v (%v)", message, err)
+	}
+	echo := message.Body.(*icmp.Echo)
+	if echo.ID != 7 || echo.Seq != 3 {
+		t.Errorf("expected the id and sequence of the request, got %v and %v", echo.ID, echo.Seq)
+	}
+	offset := echoCorruptOffset(len(data))
+	if !bytes.Equal(echo.Data[:offset], data[:offset]) {
+		t.Errorf("expected the cookie and metadata untouched")
+	}
+	if n := differentBytes(echo.Data[offset:], data[offset:]); n != 1 {
+		t.Errorf("expected 1 corrupted byte after the metadata, got %v", n)
+	}
+}
+
+func TestResponderValidateAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+		ipv6    bool
+	}{
+		{"", true, false},
+		{"127.0.0.1", true, false},
+		{"::1", true, true},
+		{"fe80::1%lo", true, true},
+		{"fe80::1%1", true, true},
+		{"localhost", false, false},
+		{"127.0.0.1%lo", false, false},
+		{"%lo", false, false},
+	}
+	for _, test := range tests {
+		r := &Responder{Probe: Probe{Protocol: probeICMP}, Address: test.address}
+		err := r.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("Validate(%q) = %v, expected valid %v", test.address, err, test.valid)
+			continue
+		}
+		if ip := r.ip(); test.valid && ip != nil && (ip.To4() == nil) != test.ipv6 {
+			t.Errorf("expected %q to be ipv6 %v", test.address, test.ipv6)
+		}
+	}
+}
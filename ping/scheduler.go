@@ -0,0 +1,110 @@
+package ping
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// represents the wait time expiry of a sent sequence
+type expiry struct {
+	deadline time.Time // time the wait time of the sequence passes
+	seq      uint64    // sequence sent
+}
+
+// min-heap of expiries ordered by deadline,
+// which implements heap.Interface
+type expiryHeap []expiry
+
+func (h expiryHeap) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap) Less(i, j int) bool {
+	return h[i].deadline.Before(h[j].deadline)
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	*h = append(*h, x.(expiry))
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// pushes an expiry, like heap.Push but without
+// boxing it, which would allocate for every send
+func (h *expiryHeap) push(e expiry) {
+	*h = append(*h, e)
+	heap.Fix(h, len(*h)-1)
+}
+
+// pops the earliest expiry, like heap.Pop but without boxing it
+func (h *expiryHeap) pop() expiry {
+	old := *h
+	e, n := old[0], len(old)-1
+	old[0] = old[n]
+	*h = old[:n]
+	if n > 0 {
+		heap.Fix(h, 0)
+	}
+	return e
+}
+
+// schedules the wait time expiries of all sent sequences on
+// a single timer owned by the processing loop, rather than
+// a timer and a goroutine for each sequence
+type scheduler struct {
+	expiries expiryHeap    // pending expiries
+	due      []uint64      // sequences whose deadline passed, reused by expire
+	mux      sync.Mutex    // mutex for expiries
+	wake     chan struct{} // notifies the processing loop of an earlier deadline
+}
+
+// creates an empty scheduler
+func newScheduler() *scheduler {
+	return &scheduler{wake: make(chan struct{}, 1)}
+}
+
+// schedules the expiry of a sequence, waking the
+// processing loop if it is now the earliest deadline
+func (s *scheduler) schedule(deadline time.Time, seq uint64) {
+	s.mux.Lock()
+	s.expiries.push(expiry{deadline: deadline, seq: seq})
+	earliest := !s.expiries[0].deadline.Before(deadline)
+	s.mux.Unlock()
+	if earliest {
+		select {
+		case s.wake <- struct{}{}:
+		default: // already notified
+		}
+	}
+}
+
+// pops the expiries whose deadline has passed, calling expire
+// for each sequence once the mutex is released, and returns the
+// time until the next deadline, or false if no expiries are
+// pending, where only the processing loop calls it
+func (s *scheduler) expire(now time.Time, expire func(seq uint64)) (time.Duration, bool) {
+	s.mux.Lock()
+	s.due = s.due[:0]
+	for len(s.expiries) > 0 && !s.expiries[0].deadline.After(now) {
+		s.due = append(s.due, s.expiries.pop().seq)
+	}
+	wait, pending := time.Duration(0), len(s.expiries) > 0
+	if pending {
+		wait = s.expiries[0].deadline.Sub(now)
+	}
+	s.mux.Unlock()
+	for _, seq := range s.due {
+		expire(seq)
+	}
+	return wait, pending
+}
@@ -1,28 +1,40 @@
 package ping
 
 import (
-	"fmt"
+	"encoding/binary"
 	"time"
 
 	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
 )
 
-// manages sending a number of ICMP echo requests
+// manages sending a number of ICMP echo requests,
+// paced at the rate if set, or the wait otherwise
 func (p *Ping) sender(done <-chan bool, errors chan<- error) {
 	defer p.waitGroup.Done()
-	// keep sending forever unless count is set
-	for i := 0; !p.Count.IsSet || i < int(p.Count.Value); i++ {
-		select {
-		case <-done:
+	pacer := newPacer(p.sendInterval(), p.Burst)
+	start, ok, err := p.sendPreload(done)
+	if err != nil {
+		go func() { errors <- err }()
+		return
+	}
+	if !ok {
+		return // stop sending
+	}
+	if start > 0 {
+		pacer.take() // the preload stands for the first send, so the cadence follows it
+	}
+	// keep sending forever unless count is set (see shouldSend)
+	for i := start; p.shouldSend(i); i++ {
+		if !pacer.wait(done) {
 			return // stop sending
-		default:
-			// send sequence i
-			err := p.send(i)
-			if err != nil {
-				go func() { errors <- err }()
-				return
-			}
-			<-time.After(time.Duration(p.Wait.Value)) // wait after sending
+		}
+		// send sequence i
+		err = p.send(i)
+		if err != nil {
+			go func() { errors <- err }()
+			return
 		}
 	}
 	go func() { errors <- nil }() // finished successfully
@@ -33,91 +45,257 @@ func (p *Ping) sender(done <-chan bool, errors chan<- error) {
 // flood mode: send 100 requests/second + as fast as they are received
 func (p *Ping) floodSender(done <-chan bool, errors chan<- error) {
 	defer p.waitGroup.Done()
-	// keep sending forever unless count is set
-	for i := 0; !p.Count.IsSet || i < int(p.Count.Value); i++ {
+	pacer := newPacer(time.Second/floodTimesPerSecond, p.Burst)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	start, ok, err := p.sendPreload(done)
+	if err != nil {
+		go func() { errors <- err }()
+		return
+	}
+	if !ok {
+		return // stop sending
+	}
+	// keep sending forever unless count is set (see shouldSend)
+	for i := start; p.shouldSend(i); i++ {
+		// wait for the next of the 100 requests/second,
+		// or for a packet to be received
+		if !timer.Stop() {
+			select {
+			case <-timer.C: // drain the fired timer
+			default:
+			}
+		}
+		timer.Reset(pacer.delay(time.Now()))
 		select {
 		case <-done:
 			return // stop sending
-		default:
-			endTime := time.Now().Add(time.Second) // send 100 req/second + as fast as they are received
-			// send 100 requests
-			for j := 0; j < floodTimesPerSecond && (!p.Count.IsSet || i < int(p.Count.Value)); i, j = i+1, j+1 {
-				err := p.send(i)
-				if err != nil {
-					go func() { errors <- err }()
-					return
-				}
-			}
-			// send as many requests as received packets until time is up
-			for time.Now().Before(endTime) && (!p.Count.IsSet || i < int(p.Count.Value)) {
-				var received bool
-				// check if a packet has been received
-				p.floodRecvMux.Lock()
-				if p.floodRecv > 0 {
-					p.floodRecv--
-					received = true
-				}
-				p.floodRecvMux.Unlock()
-				if received {
-					// packet received, so send a request
-					err := p.send(i)
-					if err != nil {
-						go func() { errors <- err }()
-						return
-					}
-					i++
-				}
-			}
-			// reset number of received packets to 0
-			p.floodRecvMux.Lock()
-			p.floodRecv = 0
-			p.floodRecvMux.Unlock()
+		case <-p.recvNotify: // packet received, so send a request
+		case <-timer.C:
+			pacer.take()
+		}
+		err = p.send(i)
+		if err != nil {
+			go func() { errors <- err }()
+			return
 		}
 	}
 	go func() { errors <- nil }() // finished successfully
 }
 
+// gets the interval between sends, from the rate if set,
+// or the wait between sending pings otherwise
+func (p *Ping) sendInterval() time.Duration {
+	if p.Rate.IsSet {
+		return time.Duration(float64(time.Second) / p.Rate.packetsPerSecond(p.PacketSize))
+	}
+	return time.Duration(p.Wait.Value)
+}
+
+// sends an "echo request" to a host for a particular
+// sequence using the Ping request's probe
+func (p *Ping) send(seq uint64) error {
+	switch p.Probe.Protocol {
+	case probeTCP:
+		return p.sendTCP(seq)
+	case probeUDP:
+		return p.sendUDP(seq)
+	case probeHTTP, probeHTTPS:
+		return p.sendHTTP(seq)
+	default:
+		return p.sendICMP(seq)
+	}
+}
+
 // sends an ICMP "echo request" to a host for a particular
 // sequence using the Ping request
-func (p *Ping) send(seq int) error {
-	// create echo request
+func (p *Ping) sendICMP(seq uint64) error {
+	// create echo request, marked with the session's cookie
+	// and followed by the metadata of the sequence
 	payload := p.PacketSize.GeneratePayload()
-	message := icmp.Message{
-		Type: p.requestType,
-		Body: &icmp.Echo{
-			ID:   p.id,
-			Seq:  seq,
-			Data: payload,
-		},
-	}
-	// marshal echo request into bytes
-	bytes, err := message.Marshal(nil)
-	if err != nil {
-		return fmt.Errorf("failed to marshal echo request: %v", err)
-	}
+	copy(payload, p.cookie)
+	sendTime := time.Now()
+	writeMetadata(payload, seq, sendTime)
+	// marshal echo request into the buffer reused for every send
+	p.sendBuffer = marshalEcho(p.sendBuffer, p.requestType, p.id, wireSeq(seq), payload)
+	bytes := p.sendBuffer
 	// add sent entry
 	p.sentMux.Lock()
-	sendTime := time.Now()
-	p.sent[seq] = &icmpPacket{
+	p.addSent(&icmpPacket{
+		seq:      seq,
 		sendTime: sendTime,
 		payload:  payload,
-	}
+	})
+	host := p.hostAddr
 	p.sentMux.Unlock()
 	// send echo request
-	_, err = p.conn.WriteTo(bytes, p.hostAddr)
+	_, err := p.conn.WriteTo(bytes, host)
 	if err != nil {
-		return fmt.Errorf("failed to send echo request: %v", err)
-	}
-	// spawn wait time check
-	go func() {
-		<-time.After(time.Duration(p.WaitTime))
-		p.sentMux.Lock()
-		packet := p.sent[seq]
-		if !packet.received {
-			// has not been seen yet, so it is late
-			packet.waitTimeExceeded = true
-		}
-		p.sentMux.Unlock()
-	}()
+		return &SendError{Op: "send echo request", Seq: seq, Err: err}
+	}
+	p.applyTxTimestamps()
+	p.checkWaitTime(seq)
 	return nil
 }
+
+// marshals an echo message into the buffer, reusing its capacity,
+// like icmp.Message.Marshal without a pseudo header: the checksum
+// is only computed for ipv4, as the kernel computes the checksum
+// of icmpv6 messages, which covers the ipv6 header
+func marshalEcho(buffer []byte, typ icmp.Type, id, seq int, data []byte) []byte {
+	var typeByte byte
+	checksum := false
+	switch typ := typ.(type) {
+	case ipv4.ICMPType:
+		typeByte, checksum = byte(typ), true
+	case ipv6.ICMPType:
+		typeByte = byte(typ)
+	}
+	buffer = append(buffer[:0], typeByte, 0, 0, 0, byte(id>>8), byte(id), byte(seq>>8), byte(seq))
+	buffer = append(buffer, data...)
+	if checksum {
+		binary.BigEndian.PutUint16(buffer[2:], internetChecksum(buffer))
+	}
+	return buffer
+}
+
+// computes the internet checksum of RFC 1071 over a message
+// whose checksum field is zero
+func internetChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8 // pad the odd byte with zero
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// schedules a wait time check for a sent sequence,
+// which is done by the processing loop
+func (p *Ping) checkWaitTime(seq uint64) {
+	p.scheduler.schedule(time.Now().Add(time.Duration(p.WaitTime)), seq)
+}
+
+// checks a sent sequence once its wait time passed, marking it
+// as late if it has not been received and releasing its payload,
+// where only late replies still need it and they are not checked
+func (p *Ping) expireWaitTime(seq uint64) {
+	p.sentMux.Lock()
+	packet, ok := p.sent.get(seq)
+	if !ok {
+		// already evicted by a later sequence, which marked it
+		// as expired if it was still in flight then
+		evicted := p.sent.expireEvicted(seq)
+		p.sentMux.Unlock()
+		if evicted {
+			p.printOutstanding(seq)
+		}
+		return
+	}
+	expired := !packet.received
+	if expired {
+		// has not been seen yet, so it is late
+		p.markExpired(packet)
+	}
+	packet.payload = nil
+	p.sentMux.Unlock()
+	if expired {
+		p.printOutstanding(seq) // output once sentMux is released
+	}
+}
+
+// marks a sent packet as having exceeded its wait time
+// without a reply, must be called with sentMux held
+func (p *Ping) markExpired(packet *icmpPacket) {
+	packet.waitTimeExceeded = true
+	p.stats.expired++
+	p.period.expired++
+	packet.period = p.periodSeq // where a late reply is counted
+	p.segments[packet.segment].stats.expired++
+	p.notifyResolved()
+	if p.Reresolve.IsSet {
+		p.countLoss()
+	}
+}
+
+// notifies that a packet was received or
+// exceeded its wait time without a reply
+func (p *Ping) notifyResolved() {
+	select {
+	case p.resolved <- struct{}{}:
+	default: // already notified
+	}
+}
+
+// gets the number of packets in flight, which were neither
+// received nor exceeded their wait time yet
+func (p *Ping) outstanding() uint64 {
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	return p.stats.transmitted - (p.stats.received - p.stats.exceeded) - p.stats.expired
+}
+
+// reports if count replies arrived
+func (p *Ping) countReceived() bool {
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	return p.Count.IsSet && p.stats.received >= uint64(p.Count.Value)
+}
+
+// reports if the sender should send a sequence, which is until
+// count packets were sent, or with a deadline, until count
+// replies arrived
+func (p *Ping) shouldSend(seq uint64) bool {
+	switch {
+	case !p.Count.IsSet:
+		return true
+	case p.Deadline.IsSet:
+		return !p.countReceived()
+	default:
+		return seq < uint64(p.Count.Value)
+	}
+}
+
+// adds a sent packet, whose sequence becomes the latest
+// sequence, to the segment of the current address of the
+// host, must be called with sentMux held
+func (p *Ping) addSent(packet *icmpPacket) {
+	packet.segment = len(p.segments) - 1
+	packet.period = p.periodSeq
+	if evicted := p.sent.add(packet); evicted != nil {
+		// no longer tracked, so its reply can only be late
+		p.markExpired(evicted)
+	}
+	p.latestSeq = packet.seq
+	sendTime := time.Now()
+	p.stats.addSent(sendTime)
+	p.period.addSent(sendTime)
+	p.segments[packet.segment].stats.addSent(sendTime)
+}
+
+// marks a sent packet as received at a time, which may be a
+// kernel timestamp, adding its round-trip time to the stats,
+// must be called with sentMux held
+func (p *Ping) markReceived(packet *icmpPacket, recvTime time.Time, kernelTime bool) {
+	packet.received = true
+	packet.receiveTime = recvTime
+	packet.kernelReceiveTime = kernelTime
+	packet.roundtripTime = recvTime.Sub(packet.sendTime)
+	p.stats.add(packet)
+	// a late reply is only counted in the periodic summary
+	// its expiry was, so received never exceeds transmitted
+	if !packet.waitTimeExceeded || packet.period == p.periodSeq {
+		p.period.add(packet)
+	}
+	p.segments[packet.segment].stats.add(packet)
+	p.lossesInRow = 0
+	p.notifyResolved()
+	if bool(p.Flood) || bool(p.Adaptive) {
+		p.notifyReceived()
+	}
+}
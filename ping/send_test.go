@@ -0,0 +1,167 @@
+package ping
+
+import (
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// represents a packet connection that turns each echo request written
+// to it into its echo reply, read back in the order written, so the
+// send and receive paths run without a socket or privileges, where
+// the fields are only set before it is used by more than one goroutine
+type echoConn struct {
+	peer     net.Addr      // address the replies come from
+	requests [][]byte      // echo requests written but not read back yet
+	discard  bool          // if the requests are dropped rather than kept
+	delay    time.Duration // if set, time before a request can be read back
+	writeErr error         // if set, error of every write
+	sent     []time.Time   // times the requests were written at
+	ready    chan struct{} // notified once a request can be read back
+	mux      sync.Mutex
+}
+
+// the time a read waits for a request before timing out
+const echoConnReadWait = 10 * time.Millisecond
+
+func (c *echoConn) ReadFrom(buffer []byte) (int, net.Addr, error) {
+	c.mux.Lock()
+	if len(c.requests) == 0 && c.ready != nil {
+		// wait for a request rather than spinning, as a socket would
+		c.mux.Unlock()
+		select {
+		case <-c.ready:
+		case <-time.After(echoConnReadWait):
+		}
+		c.mux.Lock()
+	}
+	defer c.mux.Unlock()
+	if len(c.requests) == 0 {
+		return 0, nil, errTimeout{}
+	}
+	request := c.requests[0]
+	c.requests = c.requests[1:]
+	n := copy(buffer, request)
+	buffer[0] = byte(echoReplyType) // the request, answered
+	return n, c.peer, nil
+}
+
+func (c *echoConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.writeErr != nil {
+		return 0, c.writeErr
+	}
+	c.sent = append(c.sent, time.Now())
+	if c.discard {
+		return len(b), nil
+	}
+	request := append([]byte(nil), b...)
+	if c.delay == 0 {
+		c.answer(request)
+	} else {
+		time.AfterFunc(c.delay, func() {
+			c.mux.Lock()
+			defer c.mux.Unlock()
+			c.answer(request)
+		})
+	}
+	return len(b), nil
+}
+
+// queues the reply to a request, must be called with mux held
+func (c *echoConn) answer(request []byte) {
+	c.requests = append(c.requests, request)
+	select {
+	case c.ready <- struct{}{}:
+	default: // already notified, or nobody waits
+	}
+}
+
+// gets the times the requests were written at
+func (c *echoConn) sendTimes() []time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return append([]time.Time(nil), c.sent...)
+}
+
+func (c *echoConn) Close() error                       { return nil }
+func (c *echoConn) LocalAddr() net.Addr                { return c.peer }
+func (c *echoConn) SetDeadline(t time.Time) error      { return nil }
+func (c *echoConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *echoConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// the error of reading an echoConn with no requests written
+type errTimeout struct{}
+
+func (errTimeout) Error() string   { return "i/o timeout" }
+func (errTimeout) Timeout() bool   { return true }
+func (errTimeout) Temporary() bool { return true }
+
+// type of the echo replies of an echoConn, which only answers ipv4
+const echoReplyType = 0
+
+// creates a Ping of the loopback address for tests and benchmarks,
+// whose connection is an echoConn, outputting each reply but
+// without looking up names
+func newTestPing(tb testing.TB, discard bool) *Ping {
+	tb.Helper()
+	p := &Ping{Config: DefaultConfig("127.0.0.1")}
+	p.Numeric = true
+	p.Output = io.Discard
+	if err := p.initSession(); err != nil {
+		tb.Fatalf("failed to initialize ping: %v", err)
+	}
+	p.initICMPTypes()
+	p.conn = &echoConn{peer: p.hostAddr, discard: discard}
+	return p
+}
+
+// runs a Ping of the loopback address over an echoConn, whose
+// replies are delayed, or discarded if the delay is negative,
+// returning the times the requests were sent at
+func runEcho(t *testing.T, delay time.Duration, opts ...Option) ([]time.Time, *Ping) {
+	t.Helper()
+	c := DefaultConfig("127.0.0.1")
+	c.Numeric = true
+	c.Output = io.Discard
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			t.Fatalf("invalid option: %v", err)
+		}
+	}
+	p := &Ping{Config: c, stop: make(chan struct{})}
+	p.writer = &syncWriter{w: p.output()} // replies and expiries may print at the same time, as in start()
+	if err := p.initSession(); err != nil {
+		t.Fatalf("failed to initialize ping: %v", err)
+	}
+	p.initICMPTypes()
+	conn := &echoConn{peer: p.hostAddr, discard: delay < 0, delay: delay, ready: make(chan struct{}, 1)}
+	p.conn = conn
+	if err := p.run(); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	return conn.sendTimes(), p
+}
+
+// gets the gaps between consecutive times
+func gaps(times []time.Time) []time.Duration {
+	var gaps []time.Duration
+	for i := 1; i < len(times); i++ {
+		gaps = append(gaps, times[i].Sub(times[i-1]))
+	}
+	return gaps
+}
+
+func BenchmarkSend(b *testing.B) {
+	p := newTestPing(b, true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := p.send(uint64(i)); err != nil {
+			b.Fatalf("failed to send: %v", err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package ping
+
+const (
+	// sequence constants, where sequences are 64 bits internally
+	// but only 16 bits in icmp echo requests and replies
+	seqWireBits  = 16
+	seqEpochSize = 1 << seqWireBits // sequences sent before the on-wire sequence wraps
+)
+
+// gets the on-wire sequence of an internal sequence
+func wireSeq(seq uint64) int {
+	return int(seq % seqEpochSize)
+}
+
+// maps an on-wire sequence back to the latest internal sequence
+// sent with it, returning false if no such sequence has been sent
+func unwrapSeq(wire int, latest uint64) (uint64, bool) {
+	return unwrapCounter(uint64(wire)%seqEpochSize, latest, seqEpochSize)
+}
+
+// maps a counter that wraps every epoch back to the latest value
+// it had, which is in the epoch of the latest value, or the previous
+// epoch if it would be ahead of the latest value, returning false
+// if the counter has not reached it yet
+func unwrapCounter(wrapped, latest, epochSize uint64) (uint64, bool) {
+	epoch := latest / epochSize
+	value := epoch*epochSize + wrapped
+	if value <= latest {
+		return value, true
+	}
+	if epoch == 0 {
+		return 0, false // not reached yet
+	}
+	return value - epochSize, true
+}
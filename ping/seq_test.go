@@ -0,0 +1,71 @@
+package ping
+
+import "testing"
+
+func TestUnwrapSeq(t *testing.T) {
+	tests := []struct {
+		name   string
+		wire   int
+		latest uint64
+		seq    uint64
+		ok     bool
+	}{
+		{"first epoch", 5, 10, 5, true},
+		{"latest", 10, 10, 10, true},
+		{"not sent yet", 11, 10, 0, false},
+		{"last of the first epoch", 65535, 65535, 65535, true},
+		{"wrapped once", 0, 65536, 65536, true},
+		{"wrapped twice", 3, 2*65536 + 3, 2*65536 + 3, true},
+		{"wrapped three times", 65535, 3*65536 + 65535, 3*65536 + 65535, true},
+		{"previous epoch after the wrap", 65530, 65536 + 2, 65530, true},
+		{"previous epoch after two wraps", 65000, 2*65536 + 100, 65536 + 65000, true},
+		{"ahead of the latest maps to the past", 200, 2*65536 + 100, 65536 + 200, true},
+	}
+	for _, test := range tests {
+		seq, ok := unwrapSeq(test.wire, test.latest)
+		if seq != test.seq || ok != test.ok {
+			t.Errorf("%v: unwrapSeq(%v, %v) = %v, %v, expected %v, %v",
+				test.name, test.wire, test.latest, seq, ok, test.seq, test.ok)
+		}
+		if ok && seq > test.latest {
+			t.Errorf("%v: unwrapped %v past the latest sequence %v", test.name, seq, test.latest)
+		}
+	}
+}
+
+func TestRunPastWireSeqWrap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("sends over 100000 packets")
+	}
+	const total, batch = 2*seqEpochSize + 100, 1024
+	p := newTestPing(t, false)
+	buffer := make([]byte, icmpPacketMaxSize)
+	oob := make([]byte, p.oobSize())
+	for start := uint64(0); start < total; start += batch {
+		end := start + batch
+		if end > total {
+			end = total
+		}
+		for seq := start; seq < end; seq++ {
+			if err := p.send(seq); err != nil {
+				t.Fatalf("failed to send: %v", err)
+			}
+		}
+		for seq := start; seq < end; seq++ {
+			reply, err := p.receive(buffer, oob)
+			if err != nil {
+				t.Fatalf("failed to receive: %v", err)
+			}
+			p.process(reply)
+		}
+		// each reply matched its own sequence, not one an epoch earlier
+		if packet, ok := p.sent.get(end - 1); !ok || !packet.received {
+			t.Fatalf("expected sequence %v to be received", end-1)
+		}
+	}
+	stats := p.statsSnapshot()
+	if stats.transmitted != total || stats.received != total || stats.exceeded != 0 {
+		t.Errorf("expected %v packets received in time, got %v/%v with %v late",
+			total, stats.received, stats.transmitted, stats.exceeded)
+	}
+}
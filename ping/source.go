@@ -0,0 +1,64 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	// Source constants based off the man page for 'ping'.
+	sourceFlag = "S"
+	sourceHelp = "Set the source address of outgoing packets, which must be\n" +
+		"an IPv4 or IPv6 address of this host from the same family as the\n" +
+		"host pinged. If unset, the source address is chosen by the system."
+	sourceInvalid       = "source must be a valid IPv4 or IPv6 address"
+	sourceFamilyInvalid = "source and host must be from the same address family"
+)
+
+var (
+	// errors for invalid source
+	errSourceInvalid       = errors.New(sourceInvalid)
+	errSourceFamilyInvalid = errors.New(sourceFamilyInvalid)
+)
+
+// Source is a wrapper around a boolean and an IP address
+// to use for command-line argument flag parsing.
+type Source struct {
+	IsSet bool
+	Value net.IP
+}
+
+// Init initializes a Source instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Source) Init() {
+}
+
+// String is used to format Source's value and is required
+// to satisfy the flag.Value interface.
+func (s *Source) String() string {
+	return fmt.Sprintf("set=%v, value=%v", s.IsSet, s.Value)
+}
+
+// Set will initialize Source's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (s *Source) Set(val string) error {
+	ip := net.ParseIP(val)
+	if ip == nil {
+		return errSourceInvalid
+	}
+	s.IsSet = true
+	s.Value = ip
+	return nil
+}
+
+// Flag gets the command-line flag used for Source.
+func (*Source) Flag() string {
+	return sourceFlag
+}
+
+// Help gets the command-line help for Source.
+func (*Source) Help() string {
+	return sourceHelp
+}
@@ -9,63 +9,251 @@ import (
 	"time"
 )
 
-// handles an interrupt to the program,
-// printing the ping stats before exiting
-func createInterruptHandler(p *Ping) {
-	c := make(chan os.Signal)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+// handles interrupts to the program if the Ping was started by
+// Start, rather than run by a Pinger, which leaves signals alone,
+// returning a function that stops handling them once Start returns
+func (p *Ping) handleInterrupts() (stop func()) {
+	if p.interrupt {
+		return createInterruptHandler(p)
+	}
+	return func() {}
+}
+
+// handles an interrupt to the program, stopping the run so Start
+// prints the ping stats and returns, and the info signals of the
+// system, printing interim stats without stopping, where a second
+// interrupt exits right away, returning a function that restores
+// the default handling of the signals and waits for the handler
+func createInterruptHandler(p *Ping) (stop func()) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	info := make(chan os.Signal, 1)
+	if len(infoSignals) > 0 {
+		signal.Notify(info, infoSignals...)
+	}
+	done := make(chan struct{})
+	finished := make(chan struct{})
 	go func() {
-		<-c            // received interrupt
-		p.printStats() // print stats
-		os.Exit(0)     // exit the program
+		defer close(finished)
+		c := interrupt
+		for {
+			select {
+			case <-c: // received interrupt
+				signal.Stop(c) // restore the default handling for a second one
+				c = nil        // never receive on the channel again
+				close(p.stop)  // stop the run
+			case <-info:
+				p.printInterimStats()
+			case <-done:
+				return
+			}
+		}
 	}()
+	return func() {
+		signal.Stop(interrupt)
+		signal.Stop(info)
+		close(done)
+		<-finished
+	}
+}
+
+// represents the statistics of the probes sent, updated as
+// results arrive so no history of packets needs to be kept
+type rttStats struct {
+	transmitted uint64        // packets sent
+	firstSent   time.Time     // time the first packet was sent
+	lastSent    time.Time     // time the latest packet was sent
+	received    uint64        // packets received, including late ones
+	exceeded    uint64        // packets received after their wait time
+	expired     uint64        // packets whose wait time passed without a reply
+	kernelSent  uint64        // packets received with a kernel send timestamp
+	kernelRecv  uint64        // packets received with a kernel receive timestamp
+	min         time.Duration // min rtt
+	max         time.Duration // max rtt
+	mean        float64       // mean rtt in nanoseconds
+	sumSquares  float64       // sum of squared differences from the mean (Welford's method)
+}
+
+// adds a packet sent at a time
+func (s *rttStats) addSent(sendTime time.Time) {
+	if s.transmitted == 0 {
+		s.firstSent = sendTime
+	}
+	s.lastSent = sendTime
+	s.transmitted++
+}
+
+// gets the achieved rate packets were sent at in
+// packets per second, 0 if it cannot be measured yet
+func (s *rttStats) sendRate() float64 {
+	elapsed := s.lastSent.Sub(s.firstSent)
+	if s.transmitted < 2 || elapsed <= 0 {
+		return 0
+	}
+	return float64(s.transmitted-1) / elapsed.Seconds()
 }
 
-// prints statistics
+// adds the round-trip time of a received packet
+func (s *rttStats) add(packet *icmpPacket) {
+	rtt := packet.roundtripTime
+	s.received++
+	if packet.waitTimeExceeded {
+		s.exceeded++
+	}
+	if packet.kernelSendTime {
+		s.kernelSent++
+	}
+	if packet.kernelReceiveTime {
+		s.kernelRecv++
+	}
+	if s.received == 1 || rtt < s.min {
+		s.min = rtt // found new min
+	}
+	if rtt > s.max {
+		s.max = rtt // found new max
+	}
+	delta := float64(rtt) - s.mean
+	s.mean += delta / float64(s.received)
+	s.sumSquares += delta * (float64(rtt) - s.mean)
+}
+
+// gets the percentage of packets lost, counting
+// the packets still in flight as lost
+func (s *rttStats) loss() float64 {
+	if s.transmitted == 0 || s.received >= s.transmitted {
+		return 0
+	}
+	return percentage(s.transmitted-s.received, s.transmitted)
+}
+
+// gets the percentage of packets lost among those received or past
+// their wait time, so the packets in flight do not count as lost,
+// where a late reply to a packet that exceeded its wait time in
+// an earlier periodic summary is not counted
+func (s *rttStats) resolvedLoss() float64 {
+	if s.expired <= s.exceeded {
+		return 0
+	}
+	lost := s.expired - s.exceeded
+	return percentage(lost, lost+s.received)
+}
+
+// gets the label of the host in summaries, which
+// includes the address if pinging all of them
+func (p *Ping) label() string {
+	if p.hostOverride != nil {
+		return fmt.Sprintf("%v (%v)", p.HostName, p.hostAddr.String())
+	}
+	return p.HostName
+}
+
+// gets a percentage, rounded up (formatting to 1 decimal places)
+func percentage(part, total uint64) float64 {
+	return math.Ceil(1000*float64(part)/float64(total)) / 10
+}
+
+// gets the average round-trip time
+func (s *rttStats) avg() time.Duration {
+	return time.Duration(s.mean)
+}
+
+// gets the standard deviation of the round-trip times
+func (s *rttStats) stdDev() time.Duration {
+	if s.received == 0 {
+		return 0
+	}
+	return time.Duration(math.Sqrt(s.sumSquares / float64(s.received)))
+}
+
+// prints statistics, of each address if pinging all of them
 func (p *Ping) printStats() {
-	fmt.Printf("\n--- %v ping statistics ---\n", p.HostName)
+	if p.children != nil {
+		for _, child := range p.children {
+			child.printStats()
+		}
+		if bool(p.DualStack) {
+			p.printComparison()
+		}
+		return
+	}
+	fmt.Fprintf(p.output(), "\n--- %v ping statistics ---\n", p.label())
 	p.sentMux.Lock()
 	defer p.sentMux.Unlock()
-	transmitted := len(p.sent) // number of packets sent
-	if transmitted == 0 {
-		fmt.Println("<no packets sent>")
+	stats := p.stats
+	if stats.transmitted == 0 {
+		fmt.Fprintln(p.output(), "<no packets sent>")
 		return // no packets, so no stats to show (avoid division by 0 too)
 	}
-	var received, exceeded int                  // number of packets recv, wait time exceeded
-	var min, sum, max, avg, stDev time.Duration // rtt stats
-	min = time.Duration(math.MaxInt64)          // set to max time
-	for _, packet := range p.sent {
-		if !packet.received {
-			continue // not received, so continue
+	fmt.Fprintf(p.output(), "%v packets transmitted, %v packets received, %.1f%% packet loss",
+		stats.transmitted, stats.received, stats.loss())
+	if stats.exceeded > 0 {
+		fmt.Fprintf(p.output(), ", %v packets out of wait time", stats.exceeded) // only print exceeded packets if > 0
+	}
+	fmt.Fprintln(p.output())
+	if stats.received > 0 {
+		fmt.Fprintf(p.output(), "round-trip min/avg/max/stddev = %v/%v/%v/%v\n", stats.min, stats.avg(), stats.max, stats.stdDev())
+		fmt.Fprintf(p.output(), "timestamps send/receive = %v/%v\n",
+			timestampSource(stats.kernelSent, stats.received), timestampSource(stats.kernelRecv, stats.received))
+	}
+	if p.Rate.IsSet {
+		fmt.Fprintf(p.output(), "send rate achieved/target = %.1f/%.1f packets/s\n",
+			stats.sendRate(), p.Rate.packetsPerSecond(p.PacketSize))
+	}
+	p.printSegments()
+	switch p.Probe.Protocol {
+	case probeUDP:
+		p.printUDPStats()
+	case probeHTTP, probeHTTPS:
+		p.printHTTPStats()
+	}
+}
+
+// Check reports if a finished run succeeded, returning ErrNoReply
+// if no reply arrived, a *CriteriaError if the loss or the average
+// round-trip time exceeded MaxLoss or MaxRTT, or nil otherwise.
+// If pinging all addresses, ErrNoReply is only returned if none of
+// them replied, and otherwise every address must meet the criteria,
+// so an address without a reply only fails the run with MaxLoss.
+func (p *Ping) Check() error {
+	if p.children != nil {
+		var err error
+		replied := false
+		for _, child := range p.children {
+			stats := child.statsSnapshot()
+			replied = replied || stats.received > 0
+			if childErr := child.checkCriteria(stats); childErr != nil && err == nil {
+				err = fmt.Errorf("%v: %w", child.label(), childErr)
+			}
 		}
-		received++
-		if packet.waitTimeExceeded {
-			exceeded++
+		if !replied {
+			return ErrNoReply
 		}
-		rtt := packet.roundtripTime
-		if rtt > max {
-			max = rtt // found new max
+		return err
+	}
+	stats := p.statsSnapshot()
+	if stats.received == 0 {
+		return ErrNoReply
+	}
+	return p.checkCriteria(stats)
+}
+
+// checks the loss and average round-trip time of the stats
+// against MaxLoss and MaxRTT, returning a *CriteriaError if
+// either is exceeded
+func (p *Ping) checkCriteria(stats rttStats) error {
+	switch {
+	case p.MaxLoss.IsSet && stats.loss() > p.MaxLoss.Value:
+		return &CriteriaError{
+			Criterion: maxLossFlag,
+			Limit:     fmt.Sprintf("%.1f%%", p.MaxLoss.Value),
+			Value:     fmt.Sprintf("%.1f%%", stats.loss()),
 		}
-		if rtt < min {
-			min = rtt // found new min
+	case p.MaxRTT.IsSet && stats.avg() > p.MaxRTT.Value:
+		return &CriteriaError{
+			Criterion: maxRTTFlag,
+			Limit:     p.MaxRTT.Value.String(),
+			Value:     stats.avg().String(),
 		}
-		sum += rtt // increment sum
-	}
-	// calculate average rtt
-	avg = time.Duration(sum.Nanoseconds() / int64(transmitted))
-	// used for calculating stdev
-	var sumSquaredDiff float64
-	for _, packet := range p.sent {
-		rtt := packet.roundtripTime
-		sumSquaredDiff += math.Pow(float64((rtt - avg).Nanoseconds()), 2) // add squared difference from mean
-	}
-	stDev = time.Duration(math.Sqrt(sumSquaredDiff / float64(transmitted)))             // calculate standard deviation
-	var packetLoss float64 = 100 * float64(transmitted-received) / float64(transmitted) // calculate packet loss
-	packetLoss = math.Ceil(packetLoss*10) / 10                                          // round up (formatting to 1 decimal places)
-	fmt.Printf("%v packets transmitted, %v packets received, %.1f%% packet loss",
-		transmitted, received, packetLoss)
-	if exceeded > 0 {
-		fmt.Printf(", %v packets out of wait time", exceeded) // only print exceeded packets if > 0
-	}
-	fmt.Printf("\nround-trip min/avg/max/stddev = %v/%v/%v/%v\n", min, avg, max, stDev)
+	}
+	return nil
 }
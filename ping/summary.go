@@ -0,0 +1,127 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// Summary constants for periodic summaries.
+	summaryFlag = "e"
+	summaryHelp = "Set the number of seconds between periodic summaries of the\n" +
+		"packets resolved since the previous summary, for long runs (ex.\n" +
+		"with quiet (-q)), where packets still in flight are counted in\n" +
+		"the next one. If unset, only the final statistics and interim\n" +
+		"summaries (SIGINFO/SIGQUIT) are output."
+	summaryInvalid    = "summary interval must be greater than 0"
+	summaryTimeFormat = time.RFC3339
+)
+
+var (
+	// error for invalid summary interval
+	errSummaryInvalid = errors.New(summaryInvalid)
+)
+
+// Summary is a wrapper around a boolean and a time.Duration
+// to use for command-line argument flag parsing.
+type Summary struct {
+	IsSet bool
+	Value time.Duration
+}
+
+// Init initializes a Summary instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*Summary) Init() {
+}
+
+// String is used to format Summary's value and is required
+// to satisfy the flag.Value interface.
+func (s *Summary) String() string {
+	return fmt.Sprintf("set=%v, value=%v", s.IsSet, s.Value)
+}
+
+// Set will initialize Summary's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (s *Summary) Set(val string) error {
+	res, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	if res <= 0 {
+		return errSummaryInvalid
+	}
+	s.IsSet = true
+	s.Value = time.Second * time.Duration(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for Summary.
+func (*Summary) Flag() string {
+	return summaryFlag
+}
+
+// Help gets the command-line help for Summary.
+func (*Summary) Help() string {
+	return summaryHelp
+}
+
+// prints a summary of the packets sent and received
+// since the previous one at every summary interval
+func (p *Ping) summarizer(done <-chan bool) {
+	defer p.waitGroup.Done()
+	ticker := time.NewTicker(p.Summary.Value)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			period := p.nextPeriod()
+			label := now.Format(summaryTimeFormat)
+			if p.hostOverride != nil {
+				label += " " + p.label() // one of the addresses pinged
+			}
+			printSummary(p.output(), label, period)
+		}
+	}
+}
+
+// ends the current periodic summary, returning its statistics,
+// where the packets still in flight are carried over to the next
+// one, so each is counted in the summary it is resolved in
+func (p *Ping) nextPeriod() rttStats {
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	period := p.period
+	inFlight := period.transmitted - (period.received - period.exceeded) - period.expired
+	period.transmitted -= inFlight
+	p.period = rttStats{transmitted: inFlight}
+	p.periodSeq++
+	return period
+}
+
+// prints the interim statistics of the whole run, of
+// each address if pinging all of them, without stopping it
+func (p *Ping) printInterimStats() {
+	if p.children != nil {
+		for _, child := range p.children {
+			child.printInterimStats()
+		}
+		return
+	}
+	printSummary(p.output(), p.label(), p.statsSnapshot())
+}
+
+// prints a single line summary of stats to a writer, with a label, where
+// the loss is of the packets received or past their wait time
+func printSummary(w io.Writer, label string, stats rttStats) {
+	fmt.Fprintf(w, "%v: %v/%v packets received (%.1f%% loss)", label, stats.received, stats.transmitted, stats.resolvedLoss())
+	if stats.received > 0 {
+		fmt.Fprintf(w, ", round-trip min/avg/max/stddev = %v/%v/%v/%v", stats.min, stats.avg(), stats.max, stats.stdDev())
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,64 @@
+package ping
+
+import "testing"
+
+// receives the replies to the requests an echoConn holds
+func receiveAll(t *testing.T, p *Ping) {
+	t.Helper()
+	conn := p.conn.(*echoConn)
+	buffer := make([]byte, icmpPacketMaxSize)
+	oob := make([]byte, p.oobSize())
+	for len(conn.requests) > 0 {
+		reply, err := p.receive(buffer, oob)
+		if err != nil {
+			t.Fatalf("failed to receive: %v", err)
+		}
+		p.process(reply)
+	}
+}
+
+func TestSummaryCarriesPacketsInFlight(t *testing.T) {
+	p := newTestPing(t, false)
+	for seq := uint64(0); seq < 2; seq++ {
+		if err := p.send(seq); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	// both are in flight, so counted in the next summary
+	period := p.nextPeriod()
+	if period.transmitted != 0 || period.received != 0 {
+		t.Errorf("expected nothing resolved, got %v/%v packets received", period.received, period.transmitted)
+	}
+	receiveAll(t, p)
+	period = p.nextPeriod()
+	if period.transmitted != 2 || period.received != 2 {
+		t.Errorf("expected 2/2 packets received, got %v/%v", period.received, period.transmitted)
+	}
+}
+
+func TestSummaryCountsLateReplyInItsExpiry(t *testing.T) {
+	p := newTestPing(t, false)
+	if err := p.send(0); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	p.sentMux.Lock()
+	packet, _ := p.sent.get(0)
+	p.markExpired(packet)
+	p.sentMux.Unlock()
+	period := p.nextPeriod()
+	if period.transmitted != 1 || period.received != 0 || period.resolvedLoss() != 100 {
+		t.Errorf("expected the packet to be lost, got %v/%v packets received (%v%% loss)",
+			period.received, period.transmitted, period.resolvedLoss())
+	}
+	// the late reply arrives in the next summary
+	receiveAll(t, p)
+	period = p.nextPeriod()
+	if period.transmitted != 0 || period.received != 0 {
+		t.Errorf("expected the late reply not to be counted, got %v/%v packets received",
+			period.received, period.transmitted)
+	}
+	if stats := p.statsSnapshot(); stats.received != 1 || stats.exceeded != 1 {
+		t.Errorf("expected the late reply in the stats, got %v received and %v late",
+			stats.received, stats.exceeded)
+	}
+}
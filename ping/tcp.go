@@ -0,0 +1,73 @@
+package ping
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// constants for TCP probes
+	tcpNetwork     = "tcp"
+	tcpStateOpen   = "open"     // handshake completed
+	tcpStateClosed = "closed"   // handshake refused (RST)
+	tcpStateFilter = "filtered" // no answer within the wait time
+)
+
+// sends a TCP "echo request" to a host for a particular
+// sequence using the Ping request, where the reply is
+// the result of a handshake with the probed port
+func (p *Ping) sendTCP(seq uint64) error {
+	packet := &icmpPacket{seq: seq}
+	p.sentMux.Lock()
+	p.addSent(packet)
+	p.sentMux.Unlock()
+	p.waitGroup.Add(1)
+	go p.handshake(seq, packet)
+	return nil
+}
+
+// performs the handshake for a sent TCP probe, reporting
+// the port as open, closed or filtered
+func (p *Ping) handshake(seq uint64, packet *icmpPacket) {
+	defer p.waitGroup.Done()
+	dialer := p.probeDialer()
+	p.sentMux.Lock()
+	packet.sendTime = time.Now()
+	host := p.hostAddr
+	p.sentMux.Unlock()
+	address := net.JoinHostPort(host.String(), strconv.Itoa(int(p.Probe.Port)))
+	conn, err := dialer.DialContext(p.ctx, tcpNetwork, address)
+	recvTime := time.Now()
+	if p.ctx.Err() != nil {
+		return // ping is stopping, so ignore the result
+	}
+	var state string
+	switch {
+	case err == nil:
+		conn.Close() // only needed the handshake
+		state = tcpStateOpen
+	case errors.Is(err, syscall.ECONNREFUSED):
+		state = tcpStateClosed // the host still replied
+	default:
+		p.sentMux.Lock()
+		p.markExpired(packet) // no reply from the host
+		p.sentMux.Unlock()
+		if err, ok := err.(net.Error); ok && err.Timeout() {
+			p.printReply("%v port %v: tcp_seq=%v state=%v\n",
+				p.describePeer(host), p.Probe.Port, seq, tcpStateFilter)
+		} else {
+			p.printReply("%v port %v: tcp_seq=%v error=%v\n",
+				p.describePeer(host), p.Probe.Port, seq, err)
+		}
+		return
+	}
+	p.sentMux.Lock()
+	p.markReceived(packet, recvTime, false)
+	rtt := packet.roundtripTime
+	p.sentMux.Unlock()
+	p.printReply("%v port %v: tcp_seq=%v state=%v time=%v\n",
+		p.describePeer(host), p.Probe.Port, seq, state, rtt)
+}
@@ -0,0 +1,113 @@
+package ping
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runs a single probe of a host with the options, returning the output
+func runProbe(t *testing.T, host string, opts ...Option) string {
+	t.Helper()
+	var output bytes.Buffer
+	opts = append([]Option{WithCount(1), WithNumeric(), WithOutput(&output)}, opts...)
+	p, err := New(host, opts...)
+	if err != nil {
+		t.Fatalf("New(%v) failed: %v", host, err)
+	}
+	_, err = p.Run(context.Background())
+	var socketErr *SocketError
+	if errors.As(err, &socketErr) {
+		t.Fatalf("Run() failed: %v", err)
+	}
+	return output.String()
+}
+
+// gets the port of a listener as a string
+func listenerPort(t *testing.T, listener net.Listener) string {
+	t.Helper()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to get port of %v: %v", listener.Addr(), err)
+	}
+	return port
+}
+
+func TestTCPProbeOpen(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	output := runProbe(t, "127.0.0.1", WithProbe("tcp:"+listenerPort(t, listener)))
+	if !strings.Contains(output, "tcp_seq=0 state="+tcpStateOpen+" time=") {
+		t.Errorf("expected an open port, got:\n%v", output)
+	}
+	if !strings.Contains(output, "1 packets transmitted, 1 packets received") {
+		t.Errorf("expected the handshake to count as received, got:\n%v", output)
+	}
+}
+
+func TestTCPProbeClosed(t *testing.T) {
+	listener, err := net.Listen(tcpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listenerPort(t, listener)
+	listener.Close() // nothing listens on the port anymore, so it is refused
+	output := runProbe(t, "127.0.0.1", WithProbe("tcp:"+port))
+	if !strings.Contains(output, "tcp_seq=0 state="+tcpStateClosed+" time=") {
+		t.Errorf("expected a closed port, got:\n%v", output)
+	}
+	if !strings.Contains(output, "1 packets transmitted, 1 packets received") {
+		t.Errorf("expected the refusal to count as received, got:\n%v", output)
+	}
+}
+
+// a dialer whose handshakes are never answered,
+// so each gives up once its timeout passed
+type filteredDialer struct {
+	timeout time.Duration
+}
+
+func (d filteredDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	select {
+	case <-time.After(d.timeout):
+		return nil, &net.OpError{Op: "dial", Net: network, Err: errTimeout{}}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestTCPProbeFiltered(t *testing.T) {
+	waitTime := 100 * time.Millisecond
+	resolver := &staticResolver{}
+	resolver.setAddrs("127.0.0.1")
+	var output bytes.Buffer
+	p := newResolvedPing(t, resolver, &output, WithProbe("tcp:9"),
+		WithCount(2), WithInterval(2*waitTime), WithWaitTime(waitTime))
+	p.dialOverride = filteredDialer{timeout: waitTime / 2} // gives up before the run ends
+	if err := p.run(); err != nil {
+		t.Fatalf("run() failed: %v", err)
+	}
+	if !strings.Contains(output.String(), "tcp_seq=0 state="+tcpStateFilter) {
+		t.Errorf("expected a filtered port, got:\n%v", output.String())
+	}
+	if stats := p.statsSnapshot(); stats.transmitted != 2 || stats.received != 0 || stats.expired != 2 {
+		t.Errorf("expected 2 probes without a reply, got %v/%v received and %v expired",
+			stats.received, stats.transmitted, stats.expired)
+	}
+}
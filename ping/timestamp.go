@@ -0,0 +1,50 @@
+package ping
+
+import (
+	"time"
+)
+
+const (
+	// sources of the timestamps round-trip times are taken from
+	timestampUser   = "user"   // time.Now() once the packet was sent or read
+	timestampKernel = "kernel" // socket timestamps, without userspace scheduling noise
+	timestampMixed  = "mixed"  // kernel timestamps for some packets only
+	txIDEpochSize   = 1 << 32  // sends before the id of transmit timestamps wraps
+)
+
+// gets the source of the timestamps taken for a number of
+// packets, of which some number had a kernel timestamp
+func timestampSource(kernel, total uint64) string {
+	switch {
+	case kernel == 0:
+		return timestampUser
+	case kernel == total:
+		return timestampKernel
+	default:
+		return timestampMixed
+	}
+}
+
+// applies the kernel transmit timestamps queued since the
+// last call to the packets sent, as their send time, if enabled
+func (p *Ping) applyTxTimestamps() {
+	if !p.txTimestamps {
+		return
+	}
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	readTxTimestamps(p.conn, p.txBuffer, func(id uint32, sendTime time.Time) {
+		// each send increments the id, starting from 0
+		// like the sequences, so it maps back to a sequence
+		seq, ok := unwrapCounter(uint64(id), p.latestSeq, txIDEpochSize)
+		if !ok {
+			return
+		}
+		packet, ok := p.sent.get(seq)
+		if !ok || packet.received {
+			return // evicted, or its round-trip time is already taken
+		}
+		packet.sendTime = sendTime
+		packet.kernelSendTime = true
+	})
+}
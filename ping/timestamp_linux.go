@@ -0,0 +1,143 @@
+//go:build linux
+// +build linux
+
+package ping
+
+import (
+	"net"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	// flags of SO_TIMESTAMPING, which report software transmit
+	// timestamps on the error queue, identified by a counter of
+	// sends and without the packet sent
+	sofTimestampingTxSoftware = 1 << 1
+	sofTimestampingSoftware   = 1 << 4
+	sofTimestampingOptID      = 1 << 7
+	sofTimestampingOptTSOnly  = 1 << 11
+	sofTimestampingTxFlags    = sofTimestampingTxSoftware | sofTimestampingSoftware |
+		sofTimestampingOptID | sofTimestampingOptTSOnly
+	soEEOriginTimestamping = 4 // origin of extended errors holding a transmit timestamp
+)
+
+var (
+	// space for the control messages of a receive timestamp (SCM_TIMESTAMPNS),
+	// and of software/hardware timestamps (SCM_TIMESTAMPING) set by other sockets
+	timestampOOBSize = syscall.CmsgSpace(int(unsafe.Sizeof(syscall.Timespec{}))) +
+		syscall.CmsgSpace(3*int(unsafe.Sizeof(syscall.Timespec{})))
+	// space for the control messages of a transmit timestamp on the error queue
+	txTimestampOOBSize = syscall.CmsgSpace(3*int(unsafe.Sizeof(syscall.Timespec{}))) +
+		syscall.CmsgSpace(int(unsafe.Sizeof(sockExtendedErr{}))+syscall.SizeofSockaddrInet6)
+	// space for reading a transmit timestamp, which is queued without
+	// the packet, so a single byte is read along with its control messages
+	txTimestampBufferSize = 1 + txTimestampOOBSize
+)
+
+// represents struct sock_extended_err, which holds
+// the id of a transmit timestamp in its data
+type sockExtendedErr struct {
+	errno  uint32
+	origin uint8
+	typ    uint8
+	code   uint8
+	pad    uint8
+	info   uint32
+	data   uint32
+}
+
+// enables kernel timestamps on a socket, with SO_TIMESTAMPNS for
+// received packets and SO_TIMESTAMPING for sent packets, reporting
+// which are enabled, where the system time is used otherwise
+func enableTimestamps(conn net.PacketConn) (rx, tx bool) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return false, false
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return false, false
+	}
+	raw.Control(func(fd uintptr) {
+		rx = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TIMESTAMPNS, 1) == nil
+		tx = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_TIMESTAMPING, sofTimestampingTxFlags) == nil
+	})
+	return rx, tx
+}
+
+// parses the receive timestamp of a packet from its
+// control messages, returning false if there is none
+func parseRxTimestamp(oob []byte) (time.Time, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return time.Time{}, false
+	}
+	for _, msg := range msgs {
+		if msg.Header.Level == syscall.SOL_SOCKET && msg.Header.Type == syscall.SCM_TIMESTAMPNS &&
+			len(msg.Data) >= int(unsafe.Sizeof(syscall.Timespec{})) {
+			ts := (*syscall.Timespec)(unsafe.Pointer(&msg.Data[0]))
+			return time.Unix(ts.Unix()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// reads the transmit timestamps queued on the error queue of a
+// socket without blocking, calling handle with the id and time of each,
+// where the read does not wait for the receiver, which may be blocked
+// reading the socket, and the queue would otherwise fill up the
+// receive buffer until replies are dropped, and the reads go into
+// the scratch buffer of txTimestampBufferSize bytes, reused by the caller
+func readTxTimestamps(conn net.PacketConn, scratch []byte, handle func(id uint32, sendTime time.Time)) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return
+	}
+	buffer, oob := scratch[:1], scratch[1:] // timestamps are queued without the packet
+	raw.Control(func(fd uintptr) {
+		for {
+			_, oobn, _, _, err := syscall.Recvmsg(int(fd), buffer, oob, syscall.MSG_ERRQUEUE|syscall.MSG_DONTWAIT)
+			if err != nil {
+				return // drained the error queue
+			}
+			if id, sendTime, ok := parseTxTimestamp(oob[:oobn]); ok {
+				handle(id, sendTime)
+			}
+		}
+	})
+}
+
+// parses a transmit timestamp and its id from the control
+// messages read from the error queue, returning false if
+// they do not hold one
+func parseTxTimestamp(oob []byte) (uint32, time.Time, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, time.Time{}, false
+	}
+	var sendTime time.Time
+	var serr *sockExtendedErr
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == syscall.SOL_SOCKET && msg.Header.Type == syscall.SCM_TIMESTAMPING &&
+			len(msg.Data) >= int(unsafe.Sizeof(syscall.Timespec{})):
+			// the software timestamp is the first of the three
+			ts := (*syscall.Timespec)(unsafe.Pointer(&msg.Data[0]))
+			sendTime = time.Unix(ts.Unix())
+		case (msg.Header.Level == syscall.SOL_IP && msg.Header.Type == syscall.IP_RECVERR ||
+			msg.Header.Level == syscall.SOL_IPV6 && msg.Header.Type == syscall.IPV6_RECVERR) &&
+			len(msg.Data) >= int(unsafe.Sizeof(sockExtendedErr{})):
+			serr = (*sockExtendedErr)(unsafe.Pointer(&msg.Data[0]))
+		}
+	}
+	if sendTime.IsZero() || serr == nil || serr.origin != soEEOriginTimestamping {
+		return 0, time.Time{}, false
+	}
+	return serr.data, sendTime, true
+}
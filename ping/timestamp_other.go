@@ -0,0 +1,32 @@
+//go:build !linux
+// +build !linux
+
+package ping
+
+import (
+	"net"
+	"time"
+)
+
+var (
+	// no space is needed for timestamps, which are not read on this system
+	timestampOOBSize      = 0
+	txTimestampBufferSize = 0
+)
+
+// kernel timestamps are not supported on this system,
+// so the system time is used for all packets
+func enableTimestamps(conn net.PacketConn) (rx, tx bool) {
+	return false, false
+}
+
+// receive timestamps are not supported on this system,
+// so false is returned
+func parseRxTimestamp(oob []byte) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+// transmit timestamps are not supported on this system,
+// so there are none to read
+func readTxTimestamps(conn net.PacketConn, scratch []byte, handle func(id uint32, sendTime time.Time)) {
+}
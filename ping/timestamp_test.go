@@ -0,0 +1,52 @@
+package ping
+
+import (
+	"net"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTimestampSource(t *testing.T) {
+	tests := []struct {
+		kernel, total uint64
+		source        string
+	}{
+		{0, 0, timestampUser},
+		{0, 5, timestampUser},
+		{5, 5, timestampKernel},
+		{3, 5, timestampMixed},
+	}
+	for _, test := range tests {
+		if source := timestampSource(test.kernel, test.total); source != test.source {
+			t.Errorf("timestampSource(%v, %v) = %v, expected %v", test.kernel, test.total, source, test.source)
+		}
+	}
+}
+
+func TestTimestampsReported(t *testing.T) {
+	// the sources depend on the support of the system
+	conn, err := net.ListenPacket(udpNetwork, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	rx, tx := enableTimestamps(conn)
+	conn.Close()
+	send, receive := timestampUser, timestampUser
+	if tx {
+		send = timestampKernel
+	}
+	if rx {
+		receive = timestampKernel
+	}
+	output := runUDPProbe(t, &Responder{}, WithCount(3), WithInterval(10*time.Millisecond))
+	want := "timestamps send/receive = " + send + "/" + receive + "\n"
+	if !regexp.MustCompile(regexp.QuoteMeta(want)).MatchString(output) {
+		t.Errorf("expected %q, got:\n%v", want, output)
+	}
+	for _, match := range regexp.MustCompile(`time=(\S+)`).FindAllStringSubmatch(output, -1) {
+		if rtt, err := time.ParseDuration(match[1]); err != nil || rtt <= 0 {
+			t.Errorf("expected a positive round-trip time, got %v", match[1])
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package ping
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// TypeOfService constants based off the man page for 'ping'.
+	tosFlag = "Q"
+	tosHelp = "Set the type of service (tos) byte of outgoing IPv4 packets,\n" +
+		"or the traffic class of outgoing IPv6 packets, as an integer\n" +
+		"from 0 to 255 (ex. 184 or 0xb8 for DSCP EF). The tos of each\n" +
+		"reply is output to detect re-marking on the path. If unset,\n" +
+		"the system default is used."
+	tosInvalid     = "tos must be an integer from 0 to 255"
+	tosUnsupported = "setting the tos is not supported on this system"
+	tosBitSize     = 8 // tos is a single byte
+	tosAnyPrefix   = 0 // allow decimal, hex (0x) and octal (0) values
+)
+
+var (
+	// errors for invalid tos
+	errTOSInvalid     = errors.New(tosInvalid)
+	errTOSUnsupported = errors.New(tosUnsupported)
+)
+
+// TypeOfService is a wrapper around a boolean and a byte
+// to use for command-line argument flag parsing.
+type TypeOfService struct {
+	IsSet bool
+	Value uint8
+}
+
+// Init initializes a TypeOfService instance.
+// It has an empty body since its zeroed fields
+// are sufficient.
+func (*TypeOfService) Init() {
+}
+
+// String is used to format TypeOfService's value and is required
+// to satisfy the flag.Value interface.
+func (t *TypeOfService) String() string {
+	return fmt.Sprintf("set=%v, value=%#x", t.IsSet, t.Value)
+}
+
+// Set will initialize TypeOfService's value using a string, and is
+// required to satisfy the flag.Value interface.
+func (t *TypeOfService) Set(val string) error {
+	res, err := strconv.ParseUint(val, tosAnyPrefix, tosBitSize)
+	if err != nil {
+		return errTOSInvalid
+	}
+	t.IsSet = true
+	t.Value = uint8(res)
+	return nil
+}
+
+// Flag gets the command-line flag used for TypeOfService.
+func (*TypeOfService) Flag() string {
+	return tosFlag
+}
+
+// Help gets the command-line help for TypeOfService.
+func (*TypeOfService) Help() string {
+	return tosHelp
+}
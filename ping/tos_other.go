@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package ping
+
+// setting the tos of a socket is not supported
+// on this system, so an error is returned
+func setTOS(fd uintptr, tos uint8, isIPv4 bool) error {
+	return errTOSUnsupported
+}
@@ -0,0 +1,17 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package ping
+
+import (
+	"syscall"
+)
+
+// sets the tos (ipv4) / traffic class (ipv6) of a
+// socket before it is connected
+func setTOS(fd uintptr, tos uint8, isIPv4 bool) error {
+	if isIPv4 {
+		return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, int(tos))
+	}
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, int(tos))
+}
@@ -3,18 +3,17 @@ package ping
 import (
 	"errors"
 	"fmt"
-	"log"
 	"strconv"
-	"syscall"
 )
 
 const (
 	// TTL constants based off the man page for 'ping'.
-	ttlFlag   = "m"
-	ttlSysVar = "net.inet.ip.ttl"
-	ttlHelp   = "Set the time to live (ttl) for outgoing packets as an integer.\n" +
-		"If unset, the default ttl is the system value sysctl " + ttlSysVar + "."
-	ttlInvalid = "time to live (ttl) must be greater than or equal to 0"
+	ttlFlag = "m"
+	ttlHelp = "Set the time to live (ttl) for outgoing packets as an integer.\n" +
+		"If unset, the default ttl is the system value sysctl " + ttlSysVar + ",\n" +
+		"or 64 if it cannot be queried."
+	ttlInvalid  = "time to live (ttl) must be greater than or equal to 0"
+	ttlFallback = 64 // default ttl recommended by RFC 1700, if the system value is unknown
 )
 
 var (
@@ -27,12 +26,12 @@ var (
 type TimeToLive uint32
 
 // Init initializes a TimeToLive instance by setting its
-// value to the Management Information Base (MIB) variable
-// for 'net.inet.ip.ttl'.
+// value to the system's default ttl, or to the fallback
+// if it cannot be queried (ex. in a sandbox).
 func (t *TimeToLive) Init() {
-	ttlDefault, err := syscall.SysctlUint32(ttlSysVar)
+	ttlDefault, err := defaultTTL()
 	if err != nil {
-		log.Fatalf("Failed to query sysctl for %v: %v\n", ttlSysVar, err)
+		ttlDefault = ttlFallback
 	}
 	*t = TimeToLive(ttlDefault)
 }
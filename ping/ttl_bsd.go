@@ -0,0 +1,19 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package ping
+
+import (
+	"syscall"
+)
+
+const (
+	// system variable for the default ttl
+	ttlSysVar = "net.inet.ip.ttl"
+)
+
+// gets the default ttl from the Management Information
+// Base (MIB) variable for 'net.inet.ip.ttl'
+func defaultTTL() (uint32, error) {
+	return syscall.SysctlUint32(ttlSysVar)
+}
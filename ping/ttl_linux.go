@@ -0,0 +1,30 @@
+//go:build linux
+// +build linux
+
+package ping
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// system variable for the default ttl, and its file in procfs
+	ttlSysVar     = "net.ipv4.ip_default_ttl"
+	ttlSysVarPath = "/proc/sys/net/ipv4/ip_default_ttl"
+)
+
+// gets the default ttl from the kernel parameter
+// for 'net.ipv4.ip_default_ttl'
+func defaultTTL() (uint32, error) {
+	bytes, err := os.ReadFile(ttlSysVarPath)
+	if err != nil {
+		return 0, err
+	}
+	res, err := strconv.ParseUint(strings.TrimSpace(string(bytes)), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(res), nil
+}
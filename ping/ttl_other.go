@@ -0,0 +1,15 @@
+//go:build !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !linux
+// +build !darwin,!dragonfly,!freebsd,!netbsd,!openbsd,!linux
+
+package ping
+
+const (
+	// no system variable to query, so the
+	// default ttl is the fallback (see ttl.go)
+	ttlSysVar = "<none>"
+)
+
+// gets the default ttl, which is constant on this system
+func defaultTTL() (uint32, error) {
+	return ttlFallback, nil
+}
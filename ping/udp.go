@@ -0,0 +1,182 @@
+package ping
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	// constants for UDP probes, where each datagram starts with a header
+	// that the responder reflects back, followed by a random payload
+	udpNetwork          = "udp"
+	udpAnyPort          = "0"        // lets the system choose the port
+	udpMagic            = 0x50494e47 // "PING"
+	udpMagicOffset      = 0          // magic number (uint32)
+	udpSessionOffset    = 4          // session id of the sender (uint32)
+	udpSeqOffset        = 8          // sequence (uint64)
+	udpTimestampOffset  = 16         // send time in unix nanoseconds (int64)
+	udpResponderOffset  = 24         // datagrams the responder received from the session (uint64)
+	udpHeaderSize       = 32
+	udpDatagramMaxSize  = 65535
+	udpResponderUnknown = 0 // the responder has not filled in its count
+)
+
+// represents the header of a UDP probe datagram
+type udpHeader struct {
+	session   uint32 // session id of the sender
+	seq       uint64 // sequence
+	timestamp int64  // send time in unix nanoseconds
+	responded uint64 // datagrams the responder received from the session
+}
+
+// marshals the header into the start of the datagram,
+// which must be at least udpHeaderSize bytes
+func (h *udpHeader) marshal(datagram []byte) {
+	binary.BigEndian.PutUint32(datagram[udpMagicOffset:], udpMagic)
+	binary.BigEndian.PutUint32(datagram[udpSessionOffset:], h.session)
+	binary.BigEndian.PutUint64(datagram[udpSeqOffset:], h.seq)
+	binary.BigEndian.PutUint64(datagram[udpTimestampOffset:], uint64(h.timestamp))
+	binary.BigEndian.PutUint64(datagram[udpResponderOffset:], h.responded)
+}
+
+// parses the header at the start of the datagram,
+// returning false if it is not a UDP probe datagram
+func parseUDPHeader(datagram []byte) (*udpHeader, bool) {
+	if len(datagram) < udpHeaderSize || binary.BigEndian.Uint32(datagram[udpMagicOffset:]) != udpMagic {
+		return nil, false
+	}
+	return &udpHeader{
+		session:   binary.BigEndian.Uint32(datagram[udpSessionOffset:]),
+		seq:       binary.BigEndian.Uint64(datagram[udpSeqOffset:]),
+		timestamp: int64(binary.BigEndian.Uint64(datagram[udpTimestampOffset:])),
+		responded: binary.BigEndian.Uint64(datagram[udpResponderOffset:]),
+	}, true
+}
+
+// initializes the Ping's private fields for UDP probes
+func (p *Ping) initUDP() error {
+	conn, err := p.listen(udpNetwork, "")
+	if err != nil {
+		return &SocketError{Op: "get udp conn", Err: err}
+	}
+	// set tos (ipv4) / traffic class (ipv6)
+	if p.TOS.IsSet && p.isIPv4 {
+		err = ipv4.NewPacketConn(conn).SetTOS(int(p.TOS.Value))
+	} else if p.TOS.IsSet {
+		err = ipv6.NewPacketConn(conn).SetTrafficClass(int(p.TOS.Value))
+	}
+	if err != nil {
+		conn.Close()
+		return &SocketError{Op: "set socket options", Err: err}
+	}
+	p.conn = conn
+	return nil
+}
+
+// sends a UDP "echo request" to a host for a particular
+// sequence using the Ping request, which a responder
+// is expected to reflect back
+func (p *Ping) sendUDP(seq uint64) error {
+	// create datagram with a header followed by the payload,
+	// where the header takes up the first bytes of the payload
+	payload := p.PacketSize.GeneratePayload()
+	datagram := payload
+	if len(datagram) < udpHeaderSize {
+		datagram = make([]byte, udpHeaderSize)
+		copy(datagram, payload)
+	}
+	sendTime := time.Now()
+	header := udpHeader{
+		session:   uint32(p.id),
+		seq:       seq,
+		timestamp: sendTime.UnixNano(),
+	}
+	header.marshal(datagram)
+	// add sent entry
+	p.sentMux.Lock()
+	p.addSent(&icmpPacket{
+		seq:      seq,
+		sendTime: sendTime,
+		payload:  datagram,
+	})
+	host := p.hostAddr
+	p.sentMux.Unlock()
+	// send datagram
+	_, err := p.conn.WriteTo(datagram, &net.UDPAddr{IP: host.IP, Port: int(p.Probe.Port), Zone: host.Zone})
+	if err != nil {
+		return &SendError{Op: "send udp datagram", Seq: seq, Err: err}
+	}
+	p.applyTxTimestamps()
+	p.checkWaitTime(seq)
+	return nil
+}
+
+// handles a UDP reply reflected by the responder
+func (p *Ping) handleUDPReply(reply *replyPacket) {
+	header, ok := parseUDPHeader(reply.bytes)
+	if !ok || header.session != uint32(p.id) {
+		return // not a reply to our datagrams, so ignore it
+	}
+	if !p.isHostPeer(reply.peer) {
+		return // not sent by the host, so ignore spoofed or foreign reply
+	}
+	packet, ok := p.receiveUDP(reply, header)
+	if !ok || bool(p.Quiet) {
+		return
+	}
+	fmt.Fprintf(p.output(), "%v bytes from %v: udp_seq=%v time=%v%v\n",
+		len(reply.bytes), p.describePeer(reply.peer), packet.seq, packet.roundtripTime, p.describeLate(&packet))
+	// the header is filled in by the responder, so only the rest is
+	// checked, while the payload is kept until the wait time passed
+	if packet.payload != nil {
+		printWrongByte(p.output(), packet.payload, reply.bytes, udpHeaderSize)
+	}
+}
+
+// marks the packet a UDP reply answers as received, returning a
+// copy of it for the output, which is written once sentMux is
+// released, or false if the reply answers no packet in flight
+func (p *Ping) receiveUDP(reply *replyPacket, header *udpHeader) (icmpPacket, bool) {
+	p.sentMux.Lock()
+	defer p.sentMux.Unlock()
+	// only handle new valid sequence numbers
+	seq := header.seq
+	packet, ok := p.sent.get(seq)
+	if !ok || packet.received {
+		return icmpPacket{}, false
+	}
+	p.markReceived(packet, reply.recvTime, reply.kernelTime)
+	// the count of the latest sequence tells how many of
+	// our datagrams made it to the responder
+	if header.responded != udpResponderUnknown && seq >= p.udpLatestSeq {
+		p.udpLatestSeq = seq
+		p.udpResponded = header.responded
+	}
+	return *packet, true
+}
+
+// prints the one-way loss of UDP probes, inferred from the count
+// of datagrams that the responder received up to the latest
+// sequence it replied to, must be called with sentMux held
+func (p *Ping) printUDPStats() {
+	if p.udpResponded == udpResponderUnknown {
+		return // no replies, so the direction cannot be inferred
+	}
+	// every reply either is the latest sequence or is before it,
+	// so all replies received are up to the latest sequence
+	received := int64(p.stats.received)
+	forward := int64(p.udpLatestSeq+1) - int64(p.udpResponded) // lost on the way to the responder
+	reverse := int64(p.udpResponded) - received                // lost on the way back
+	if forward < 0 {
+		forward = 0 // reordered datagrams
+	}
+	if reverse < 0 {
+		reverse = 0
+	}
+	fmt.Fprintf(p.output(), "one-way loss: %v packets to host, %v packets from host\n", forward, reverse)
+}
@@ -0,0 +1,85 @@
+package ping
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// matches the one-way loss of udp probes
+var udpOneWayPattern = regexp.MustCompile(`one-way loss: (\d+) packets to host, (\d+) packets from host`)
+
+// matches the statistics of a run
+var udpStatsPattern = regexp.MustCompile(`(\d+) packets transmitted, (\d+) packets received`)
+
+// runs udp probes of a responder on a loopback socket,
+// returning the output
+func runUDPProbe(t *testing.T, r *Responder, opts ...Option) string {
+	t.Helper()
+	r.Probe = Probe{Protocol: probeUDP}
+	addr := listenResponder(t, r, r.serveUDP).(*net.UDPAddr)
+	opts = append(opts, WithProbe(probeUDP+":"+strconv.Itoa(addr.Port)))
+	return runProbe(t, addr.IP.String(), opts...)
+}
+
+// gets the numbers a pattern captures in the output
+func captureCounts(t *testing.T, pattern *regexp.Regexp, output string) (int, int) {
+	t.Helper()
+	match := pattern.FindStringSubmatch(output)
+	if match == nil {
+		t.Fatalf("expected a match of %v, got:\n%v", pattern, output)
+	}
+	a, _ := strconv.Atoi(match[1])
+	b, _ := strconv.Atoi(match[2])
+	return a, b
+}
+
+func TestUDPProbeReplies(t *testing.T) {
+	output := runUDPProbe(t, &Responder{}, WithCount(3), WithInterval(10*time.Millisecond))
+	for seq := 0; seq < 3; seq++ {
+		if !regexp.MustCompile(`bytes from 127\.0\.0\.1: udp_seq=` + strconv.Itoa(seq) + ` .*time=`).MatchString(output) {
+			t.Errorf("expected a reply to udp_seq=%v, got:\n%v", seq, output)
+		}
+	}
+	if forward, reverse := captureCounts(t, udpOneWayPattern, output); forward != 0 || reverse != 0 {
+		t.Errorf("expected no one-way loss, got %v to and %v from the host", forward, reverse)
+	}
+}
+
+func TestUDPProbeLossFromHost(t *testing.T) {
+	// the responder counts the requests before dropping the
+	// replies, so the loss is inferred on the way back
+	output := runUDPProbe(t, &Responder{Loss: 50}, WithCount(20),
+		WithInterval(time.Millisecond), WithWaitTime(100*time.Millisecond))
+	transmitted, received := captureCounts(t, udpStatsPattern, output)
+	if received == 0 || received == transmitted {
+		t.Skipf("the random loss dropped none or all of the %v replies", transmitted)
+	}
+	forward, reverse := captureCounts(t, udpOneWayPattern, output)
+	if forward != 0 {
+		t.Errorf("expected no loss to the host, got %v", forward)
+	}
+	// replies dropped after the latest one received are not in its count
+	if reverse == 0 || reverse > transmitted-received {
+		t.Errorf("expected up to %v packets lost from the host, got %v", transmitted-received, reverse)
+	}
+}
+
+func TestUDPProbeLateReply(t *testing.T) {
+	// the reply to the first probe arrives after its wait time,
+	// while the second probe is still in flight
+	output := runUDPProbe(t, &Responder{Delay: Delay(100 * time.Millisecond)}, WithCount(2),
+		WithInterval(150*time.Millisecond), WithWaitTime(30*time.Millisecond), WithOutstanding())
+	if !regexp.MustCompile(`no answer yet for udp_seq=0\n`).MatchString(output) {
+		t.Errorf("expected the first probe to be outstanding, got:\n%v", output)
+	}
+	match := regexp.MustCompile(`udp_seq=0 .*time=\S+ \(late, (\S+)ms\)`).FindStringSubmatch(output)
+	if match == nil {
+		t.Fatalf("expected a late reply to udp_seq=0, got:\n%v", output)
+	}
+	if late, err := strconv.ParseFloat(match[1], 64); err != nil || late < 60 {
+		t.Errorf("expected the reply about 70ms late, got %vms", match[1])
+	}
+}
@@ -3,6 +3,7 @@ package ping
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 )
@@ -13,8 +14,8 @@ const (
 	waitHelp = "Set the number of seconds to wait between sending each packet.\n" +
 		"The number can be a fraction (ex. 0.1). If unset,\n" +
 		"the default is a one second interval between packets.\n" +
-		"This flag (-i) is incompatible with flood (-f)."
-	waitInvalid      = "count must be greater than or equal to 0"
+		"This flag (-i) is incompatible with flood (-f) and rate (-r)."
+	waitInvalid      = "wait must be a number of seconds greater than or equal to 0"
 	waitDefault      = time.Second
 	waitInputBitSize = 64 // float64 accepted as input, so need 64 bits
 )
@@ -49,7 +50,9 @@ func (w *Wait) Set(val string) error {
 	if err != nil {
 		return err
 	}
-	if res < 0 {
+	// the wait must fit a time.Duration, which also rules out infinity,
+	// where the bound itself rounds up to 2^63 nanoseconds and overflows
+	if math.IsNaN(res) || res < 0 || res >= float64(math.MaxInt64)/float64(time.Second) {
 		return errWaitInvalid
 	}
 	w.IsSet = true
@@ -0,0 +1,35 @@
+package ping
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWaitBound(t *testing.T) {
+	bound := float64(math.MaxInt64) / float64(time.Second)
+	tests := []struct {
+		val   string
+		valid bool
+	}{
+		{"0", true},
+		{"0.1", true},
+		{"9223372036", true},
+		{strconv.FormatFloat(bound, 'f', -1, 64), false}, // overflows once converted
+		{"9223372037", false},
+		{"-1", false},
+		{"NaN", false},
+		{"Inf", false},
+	}
+	for _, test := range tests {
+		var w Wait
+		err := w.Set(test.val)
+		if (err == nil) != test.valid {
+			t.Errorf("Set(%v) = %v, expected valid %v", test.val, err, test.valid)
+		}
+		if err == nil && w.Value < 0 {
+			t.Errorf("Set(%v) overflowed to %v", test.val, w.Value)
+		}
+	}
+}
@@ -12,8 +12,9 @@ const (
 	waitTimeFlag = "W"
 	waitTimeHelp = "Set the time in milliseconds to wait for a reply with\n" +
 		"each packet sent. If a reply arrives after the interval,\n" +
-		"it is not printed, but counted as a replied packet\n" +
-		"for the statistics. If unset, waittime is 4 seconds."
+		"it is printed as late, and counted as a replied packet\n" +
+		"for the statistics. If unset, waittime is 4 seconds. With\n" +
+		"0, tcp and http probes still give up after 4 seconds."
 	waitTimeInvalid       = "waittime must be greater than or equal to 0"
 	waitTimeDefaultMillis = 4000
 )
@@ -53,6 +54,17 @@ func (w *WaitTime) Set(val string) error {
 	return nil
 }
 
+// gets the time a tcp or http probe waits for its handshake or
+// response, which is the wait time, or the default wait time if
+// it is 0, as the probe would otherwise never give up on a host
+// that does not answer
+func (p *Ping) probeTimeout() time.Duration {
+	if p.WaitTime == 0 {
+		return time.Millisecond * waitTimeDefaultMillis
+	}
+	return time.Duration(p.WaitTime)
+}
+
 // Flag gets the command-line flag used for WaitTime.
 func (*WaitTime) Flag() string {
 	return waitTimeFlag
@@ -0,0 +1,19 @@
+package ping
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProbeTimeout(t *testing.T) {
+	p := &Ping{Config: DefaultConfig("127.0.0.1")}
+	p.WaitTime = WaitTime(200 * time.Millisecond)
+	if timeout := p.dialer().Timeout; timeout != 200*time.Millisecond {
+		t.Errorf("expected the wait time as the dial timeout, got %v", timeout)
+	}
+	// tcp and http probes never give up without a timeout
+	p.WaitTime = 0
+	if timeout := p.dialer().Timeout; timeout != time.Millisecond*waitTimeDefaultMillis {
+		t.Errorf("expected the default wait time as the dial timeout, got %v", timeout)
+	}
+}
@@ -0,0 +1,96 @@
+package ping
+
+const (
+	// packets tracked while in flight, one for each on-wire
+	// sequence, so a reply always maps to a single slot
+	windowSize  = seqEpochSize
+	bitsPerWord = 64
+)
+
+// represents the packets in flight as a fixed-size ring indexed by
+// sequence, so memory stays constant however long a Ping runs,
+// where a packet is evicted once a later sequence reuses its slot
+type window struct {
+	slots     []*icmpPacket // packets by sequence modulo the window size
+	received  bitset        // by slot, set if the packet evicted from the slot was received
+	unexpired bitset        // by slot, set if the packet evicted from the slot had not reached its wait time
+}
+
+// represents a set of bits, one for each slot of a window
+type bitset []uint64
+
+// creates an empty window
+func newWindow() window {
+	return window{
+		slots:     make([]*icmpPacket, windowSize),
+		received:  make(bitset, windowSize/bitsPerWord),
+		unexpired: make(bitset, windowSize/bitsPerWord),
+	}
+}
+
+// adds a sent packet, evicting the packet sent with the same
+// slot a window earlier, if any, whose reply is only counted
+// once after that, returning the evicted packet if it was
+// neither received nor past its wait time, so it must be
+// marked as expired now that it is no longer tracked
+func (w *window) add(packet *icmpPacket) (unresolved *icmpPacket) {
+	slot := packet.seq % windowSize
+	evicted := w.slots[slot]
+	w.slots[slot] = packet
+	w.received.set(slot, evicted == nil || evicted.received)
+	w.unexpired.set(slot, evicted != nil && !evicted.received && !evicted.waitTimeExceeded)
+	if w.unexpired.get(slot) {
+		return evicted
+	}
+	return nil
+}
+
+// reports if an evicted sequence was evicted before its wait
+// time passed, which is only reported once, so its wait time
+// check can still report it as outstanding
+func (w *window) expireEvicted(seq uint64) bool {
+	slot := seq % windowSize
+	packet := w.slots[slot]
+	if packet == nil || packet.seq != seq+windowSize || !w.unexpired.get(slot) {
+		return false
+	}
+	w.unexpired.set(slot, false)
+	return true
+}
+
+// marks an evicted sequence as received, returning false if it was
+// already received, or if it was sent more than a window before the
+// packet in its slot, when it is too old to tell
+func (w *window) receiveEvicted(seq uint64) bool {
+	slot := seq % windowSize
+	packet := w.slots[slot]
+	if packet == nil || packet.seq != seq+windowSize || w.received.get(slot) {
+		return false
+	}
+	w.received.set(slot, true)
+	return true
+}
+
+// gets the bit of a slot
+func (b bitset) get(slot uint64) bool {
+	return b[slot/bitsPerWord]&(1<<(slot%bitsPerWord)) != 0
+}
+
+// sets the bit of a slot
+func (b bitset) set(slot uint64, value bool) {
+	if value {
+		b[slot/bitsPerWord] |= 1 << (slot % bitsPerWord)
+	} else {
+		b[slot/bitsPerWord] &^= 1 << (slot % bitsPerWord)
+	}
+}
+
+// gets the packet sent for a sequence, returning
+// false if it was never sent or has been evicted
+func (w *window) get(seq uint64) (*icmpPacket, bool) {
+	packet := w.slots[seq%windowSize]
+	if packet == nil || packet.seq != seq {
+		return nil, false
+	}
+	return packet, true
+}
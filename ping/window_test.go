@@ -0,0 +1,37 @@
+package ping
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEvictedPacketExpires(t *testing.T) {
+	p := newTestPing(t, true)
+	var output bytes.Buffer
+	p.Output = &output
+	p.Outstanding = true
+	// the window wraps before the wait time of the first sequence
+	for seq := uint64(0); seq <= windowSize; seq++ {
+		if err := p.send(seq); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	if outstanding := p.outstanding(); outstanding != windowSize {
+		t.Errorf("expected %v packets in flight, got %v", windowSize, outstanding)
+	}
+	// its wait time passes after it was evicted
+	p.expireWaitTime(0)
+	p.expireWaitTime(0)
+	stats := p.statsSnapshot()
+	if stats.expired != 1 {
+		t.Errorf("expected the evicted packet to expire once, got %v expired", stats.expired)
+	}
+	if lines := strings.Count(output.String(), "no answer yet for icmp_seq=0\n"); lines != 1 {
+		t.Errorf("expected the evicted packet to be reported once, got:\n%v", output.String())
+	}
+	if period := p.nextPeriod(); period.transmitted != 1 || period.resolvedLoss() != 100 {
+		t.Errorf("expected the evicted packet to be lost in the summary, got %v/%v packets received",
+			period.received, period.transmitted)
+	}
+}